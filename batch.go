@@ -9,17 +9,33 @@ import (
 // Batch returns a Pipeline that batches items from the input Stream into slices of n items.
 // If the batch is not full after maxWait, it will be sent anyway.
 // Any error in the input Stream will be propagated to the output Stream immediately.
+// Within a pipeline traced with WithTracer, each batch has its own span, linked to the spans of its items.
 func Batch[T any](n int, opt ...BatchOption) Pipeline[T, []T] {
 	o := assertBatchOptions(opt)
 
 	return func(ctx context.Context, in Stream[T], errs chan<- error) Stream[[]T] {
 		out := make(chan []T, o.bufferSize)
 
+		if describeStage(ctx, TopologyNode{Kind: "Batch", BufferSize: o.bufferSize}, []any{in}, []any{Stream[[]T](out)}) {
+			go func() {
+				defer close(out)
+				drain(in)
+			}()
+			return out
+		}
+
+		obs := ObserveStage(ctx, "Batch")
+		tr := traceStage(ctx, "Batch", in, out)
+
 		go func() {
+			labelGoroutine(ctx, "Batch", -1)
+
 			defer close(out)
 
 			batch := make([]T, 0, n)
 
+			var links []SpanContext
+
 			copyBatch := func() []T {
 				batchCopy := make([]T, len(batch))
 				copy(batchCopy, batch)
@@ -27,18 +43,30 @@ func Batch[T any](n int, opt ...BatchOption) Pipeline[T, []T] {
 			}
 
 			sendBatch := func() (exit bool) {
-				if len(batch) > 0 {
-					select {
-					case out <- copyBatch():
-						batch = batch[:0]
-					case <-ctx.Done():
+				if len(batch) == 0 {
+					return false
+				}
+
+				if tr != nil {
+					_, span := tr.start(ctx, SpanContext{}, links...)
+					span.SetAttribute("rivo.batch.size", len(batch))
+					sent := tracedSend(ctx, obs, tr.out, out, copyBatch(), span.SpanContext())
+					span.End(nil)
+					if !sent {
 						return true
 					}
+					links = nil
+				} else if !observedSend(ctx, obs, out, copyBatch()) {
+					return true
 				}
+
+				batch = batch[:0]
 				return false
 			}
 
 			for {
+				start := time.Now()
+
 				select {
 				case item, ok := <-in:
 					if !ok {
@@ -46,8 +74,16 @@ func Batch[T any](n int, opt ...BatchOption) Pipeline[T, []T] {
 						return
 					}
 
+					obs.ItemReceived(time.Since(start))
+
 					batch = append(batch, item)
 
+					if tr != nil {
+						if parent := tr.parent(); parent.IsValid() {
+							links = append(links, parent)
+						}
+					}
+
 					if len(batch) == n {
 						if exit := sendBatch(); exit {
 							return
@@ -15,6 +15,10 @@ func ToWriter(w *bufio.Writer) rivo.Pipeline[[]byte, int] {
 	return func(ctx context.Context, in rivo.Stream[[]byte], errs chan<- error) rivo.Stream[int] {
 		out := make(chan int)
 
+		if rivo.DescribeStage(ctx, rivo.TopologyNode{Kind: "bufio.ToWriter"}, in, out) {
+			return out
+		}
+
 		go func() {
 			defer close(out)
 			defer func() {
@@ -0,0 +1,42 @@
+package rivo
+
+import "context"
+
+// ChainBuilder composes any number of pipelines whose input and output types are the same. See Chain.
+// A ChainBuilder is immutable: Then returns a new builder, so that a chain can be extended in different ways.
+type ChainBuilder[T any] struct {
+	stages []Pipeline[T, T]
+}
+
+// Chain returns an empty ChainBuilder. It's an alternative to Pipe for long sequences of stages of the same type:
+//
+//	p := Chain[Order]().Then(validate).Then(enrich).Then(audit).Pipeline()
+func Chain[T any]() ChainBuilder[T] {
+	return ChainBuilder[T]{}
+}
+
+// Then returns a builder that appends the given pipelines to the chain.
+func (c ChainBuilder[T]) Then(p ...Pipeline[T, T]) ChainBuilder[T] {
+	stages := make([]Pipeline[T, T], 0, len(c.stages)+len(p))
+	stages = append(stages, c.stages...)
+	stages = append(stages, p...)
+
+	return ChainBuilder[T]{stages: stages}
+}
+
+// Pipeline returns a pipeline that runs the stages of the chain in order, as Pipe does.
+// An empty chain returns its input stream unchanged.
+func (c ChainBuilder[T]) Pipeline() Pipeline[T, T] {
+	stages := c.stages
+
+	return func(ctx context.Context, in Stream[T], errs chan<- error) Stream[T] {
+		for i, p := range stages {
+			if i == 0 {
+				in = p(ctx, in, errs)
+				continue
+			}
+			in = p(context.WithoutCancel(ctx), in, errs)
+		}
+		return in
+	}
+}
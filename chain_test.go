@@ -0,0 +1,88 @@
+package rivo_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	. "github.com/agiac/rivo"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func ExampleChain() {
+	ctx := context.Background()
+
+	even := Filter(func(ctx context.Context, n int) (bool, error) {
+		return n%2 == 0, nil
+	})
+
+	double := Map(func(ctx context.Context, n int) (int, error) {
+		return n * 2, nil
+	})
+
+	p := Chain[int]().Then(even).Then(double).Pipeline()
+
+	got, _ := RunCollect(ctx, Pipe(Of(1, 2, 3, 4, 5), p))
+
+	fmt.Println(got)
+
+	// Output:
+	// [4 8]
+}
+
+func TestChain(t *testing.T) {
+	addOne := Map(func(ctx context.Context, n int) (int, error) {
+		return n + 1, nil
+	})
+
+	double := Map(func(ctx context.Context, n int) (int, error) {
+		return n * 2, nil
+	})
+
+	t.Run("compose stages in order", func(t *testing.T) {
+		ctx := context.Background()
+
+		p := Chain[int]().Then(addOne).Then(double, addOne).Pipeline()
+
+		got, err := RunCollect(ctx, Pipe(Of(1, 2, 3), p))
+
+		assert.NoError(t, err)
+		assert.Equal(t, []int{5, 7, 9}, got)
+	})
+
+	t.Run("empty chain", func(t *testing.T) {
+		ctx := context.Background()
+
+		got, err := RunCollect(ctx, Pipe(Of(1, 2, 3), Chain[int]().Pipeline()))
+
+		assert.NoError(t, err)
+		assert.Equal(t, []int{1, 2, 3}, got)
+	})
+
+	t.Run("builders are immutable", func(t *testing.T) {
+		ctx := context.Background()
+
+		base := Chain[int]().Then(addOne)
+		a := base.Then(double).Pipeline()
+		b := base.Then(addOne).Pipeline()
+
+		gotA, _ := RunCollect(ctx, Pipe(Of(1), a))
+		gotB, _ := RunCollect(ctx, Pipe(Of(1), b))
+
+		assert.Equal(t, []int{4}, gotA)
+		assert.Equal(t, []int{3}, gotB)
+	})
+
+	t.Run("context cancellation", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		in := make(chan int)
+		defer close(in)
+
+		got := Collect(Chain[int]().Then(addOne, double).Pipeline()(ctx, in, nil))
+
+		assert.Empty(t, got)
+	})
+}
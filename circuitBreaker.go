@@ -0,0 +1,435 @@
+package rivo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is the error of the items rejected by an open circuit breaker.
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// CircuitState is the state of a CircuitBreaker.
+type CircuitState int
+
+const (
+	// CircuitClosed lets every call through, recording its outcome.
+	CircuitClosed CircuitState = iota
+	// CircuitOpen rejects every call until the open timeout has passed.
+	CircuitOpen
+	// CircuitHalfOpen lets a limited number of trial calls through: the circuit is closed again if they all succeed
+	// and opened again as soon as one of them fails.
+	CircuitHalfOpen
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	default:
+		return fmt.Sprintf("CircuitState(%d)", int(s))
+	}
+}
+
+// CircuitBreaker protects a dependency from being called while it's failing.
+// It's opened when the failure rate of the latest calls, over a rolling window, reaches a threshold. It's safe for
+// concurrent use and can be shared by several pipelines calling the same dependency.
+type CircuitBreaker struct {
+	o *circuitBreakerOptions
+
+	mu       sync.Mutex
+	state    CircuitState
+	outcomes []bool // ring buffer of the latest outcomes, true for failures
+	next     int
+	calls    int
+	failures int
+	openedAt time.Time
+	trials   int
+	passed   int
+	epoch    uint64 // incremented on every state transition
+}
+
+// NewCircuitBreaker returns a closed CircuitBreaker.
+// By default, it's opened when at least half of the latest 20 calls failed, considering at least 10 calls,
+// it stays open for 30s and then lets 1 trial call through.
+// NewCircuitBreaker panics if invalid options are provided.
+func NewCircuitBreaker(opt ...CircuitBreakerOption) *CircuitBreaker {
+	o := assertCircuitBreakerOptions(opt)
+
+	return &CircuitBreaker{
+		o:        o,
+		state:    CircuitClosed,
+		outcomes: make([]bool, o.windowSize),
+	}
+}
+
+// State returns the current state of the circuit breaker.
+func (b *CircuitBreaker) State() CircuitState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == CircuitOpen && b.openTimeoutPassed() {
+		return CircuitHalfOpen
+	}
+
+	return b.state
+}
+
+// allow reports whether a call can be made. If it returns true, the outcome of the call must be recorded, together
+// with the returned epoch, i.e. the state in which the call was allowed.
+func (b *CircuitBreaker) allow() (bool, uint64) {
+	b.mu.Lock()
+
+	from := b.state
+
+	if b.state == CircuitOpen && b.openTimeoutPassed() {
+		b.setState(CircuitHalfOpen)
+	}
+
+	allowed := true
+
+	switch b.state {
+	case CircuitOpen:
+		allowed = false
+	case CircuitHalfOpen:
+		allowed = b.trials < b.o.halfOpenCalls
+		if allowed {
+			b.trials++
+		}
+	}
+
+	to := b.state
+	epoch := b.epoch
+
+	b.mu.Unlock()
+
+	b.notify(from, to)
+
+	return allowed, epoch
+}
+
+// record records whether a call allowed by the circuit breaker in the given epoch failed. The outcomes of the calls
+// allowed before the latest state transition are discarded, so that, for example, the calls allowed while closed
+// are not counted as trial calls once half-open.
+func (b *CircuitBreaker) record(epoch uint64, failed bool) {
+	b.mu.Lock()
+
+	if epoch != b.epoch {
+		b.mu.Unlock()
+		return
+	}
+
+	from := b.state
+
+	switch b.state {
+	case CircuitClosed:
+		if b.calls == len(b.outcomes) {
+			if b.outcomes[b.next] {
+				b.failures--
+			}
+		} else {
+			b.calls++
+		}
+
+		b.outcomes[b.next] = failed
+		b.next = (b.next + 1) % len(b.outcomes)
+
+		if failed {
+			b.failures++
+		}
+
+		if b.calls >= b.o.minCalls && float64(b.failures)/float64(b.calls) >= b.o.failureRate {
+			b.setState(CircuitOpen)
+		}
+	case CircuitHalfOpen:
+		if failed {
+			b.setState(CircuitOpen)
+			break
+		}
+
+		b.passed++
+		if b.passed == b.o.halfOpenCalls {
+			b.setState(CircuitClosed)
+		}
+	}
+
+	to := b.state
+
+	b.mu.Unlock()
+
+	b.notify(from, to)
+}
+
+func (b *CircuitBreaker) openTimeoutPassed() bool {
+	return !b.o.clock.Now().Before(b.openedAt.Add(b.o.openTimeout))
+}
+
+// setState moves the circuit breaker to the given state, resetting the statistics of the previous one.
+func (b *CircuitBreaker) setState(s CircuitState) {
+	b.state = s
+	b.epoch++
+	b.trials = 0
+	b.passed = 0
+
+	switch s {
+	case CircuitOpen:
+		b.openedAt = b.o.clock.Now()
+	case CircuitClosed:
+		b.next = 0
+		b.calls = 0
+		b.failures = 0
+	}
+}
+
+func (b *CircuitBreaker) notify(from, to CircuitState) {
+	if from != to && b.o.onStateChange != nil {
+		b.o.onStateChange(from, to)
+	}
+}
+
+// CircuitBreak returns a pipeline that applies a function to each item from the input stream through the given
+// circuit breaker. The failures of the function are sent to the error channel, as with Map, and recorded by the
+// circuit breaker; while it's open, the function is not called and the items are handled by the fallback instead.
+// The function set with ForEachOutputOnBeforeClose, if any, is called before the fallback is closed.
+// CircuitBreak panics if invalid options are provided.
+func CircuitBreak[T, U any](cb *CircuitBreaker, f func(context.Context, T) (U, error), fallback CircuitFallback[T, U], opt ...ForEachOutputOption) Pipeline[T, U] {
+	if fallback.handle == nil {
+		fallback = CircuitFallbackError[T, U]()
+	}
+
+	opt = append(opt, forEachOutputChainOnBeforeClose(func(ctx context.Context) {
+		fallback.close()
+	}), forEachOutputKind("CircuitBreak"), forEachOutputTimeoutCalls())
+
+	return ForEachOutput[T, U](func(ctx context.Context, val T, out chan<- U, errs chan<- error) {
+		allowed, epoch := cb.allow()
+		if !allowed {
+			fallback.handle(ctx, val, out, errs)
+			return
+		}
+
+		callCtx, discard := callContext(ctx)
+		v, err := circuitCall(callCtx, cb, epoch, f, val)
+		if discard() {
+			return
+		}
+
+		if err != nil {
+			select {
+			case <-ctx.Done():
+			case errs <- err:
+			}
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+		case out <- v:
+		}
+	}, opt...)
+}
+
+// circuitCall calls f and records its outcome in the circuit breaker. The calls that panic or time out count as
+// failures, so that a trial call always settles the state of a half-open circuit breaker.
+func circuitCall[T, U any](ctx context.Context, cb *CircuitBreaker, epoch uint64, f func(context.Context, T) (U, error), val T) (v U, err error) {
+	failed := true
+	defer func() {
+		cb.record(epoch, failed)
+	}()
+
+	v, err = f(ctx, val)
+	failed = (err != nil && cb.o.isFailure(err)) || errors.Is(ctx.Err(), context.DeadlineExceeded)
+
+	return v, err
+}
+
+// CircuitFallback handles the items rejected by an open circuit breaker. The zero value is CircuitFallbackError.
+type CircuitFallback[T, U any] struct {
+	handle func(ctx context.Context, val T, out chan<- U, errs chan<- error)
+	close  func()
+}
+
+// CircuitFallbackError returns a fallback that sends an ItemError wrapping ErrCircuitOpen to the error channel for each rejected item.
+func CircuitFallbackError[T, U any]() CircuitFallback[T, U] {
+	return CircuitFallback[T, U]{
+		handle: func(ctx context.Context, val T, out chan<- U, errs chan<- error) {
+			select {
+			case <-ctx.Done():
+			case errs <- &ItemError{Item: val, Err: ErrCircuitOpen}:
+			}
+		},
+		close: func() {},
+	}
+}
+
+// CircuitFallbackDrop returns a fallback that silently discards the rejected items.
+func CircuitFallbackDrop[T, U any]() CircuitFallback[T, U] {
+	return CircuitFallback[T, U]{
+		handle: func(ctx context.Context, val T, out chan<- U, errs chan<- error) {},
+		close:  func() {},
+	}
+}
+
+// CircuitFallbackDefault returns a fallback that emits the given value in place of each rejected item.
+func CircuitFallbackDefault[T, U any](v U) CircuitFallback[T, U] {
+	return CircuitFallback[T, U]{
+		handle: func(ctx context.Context, val T, out chan<- U, errs chan<- error) {
+			select {
+			case <-ctx.Done():
+			case out <- v:
+			}
+		},
+		close: func() {},
+	}
+}
+
+// CircuitFallbackRoute returns a stream and a fallback that sends the rejected items to that stream, for example
+// to retry them later. The stream must be consumed concurrently with the output of the pipeline, otherwise the
+// pipeline is blocked, and it's closed when the output of the pipeline is closed. The fallback must be used with a
+// single pipeline.
+func CircuitFallbackRoute[T, U any]() (Stream[T], CircuitFallback[T, U]) {
+	rejected := make(chan T)
+
+	return rejected, CircuitFallback[T, U]{
+		handle: func(ctx context.Context, val T, out chan<- U, errs chan<- error) {
+			select {
+			case <-ctx.Done():
+			case rejected <- val:
+			}
+		},
+		close: sync.OnceFunc(func() { close(rejected) }),
+	}
+}
+
+type circuitBreakerOptions struct {
+	failureRate   float64
+	windowSize    int
+	minCalls      int
+	openTimeout   time.Duration
+	halfOpenCalls int
+	isFailure     func(error) bool
+	onStateChange func(from, to CircuitState)
+	clock         Clock
+}
+
+type CircuitBreakerOption func(*circuitBreakerOptions) error
+
+// CircuitBreakerFailureRate sets the failure rate, between 0 and 1, at which the circuit breaker is opened.
+func CircuitBreakerFailureRate(rate float64) CircuitBreakerOption {
+	return func(o *circuitBreakerOptions) error {
+		if rate <= 0 || rate > 1 {
+			return fmt.Errorf("failureRate must be greater than 0 and less than or equal to 1")
+		}
+		o.failureRate = rate
+		return nil
+	}
+}
+
+// CircuitBreakerWindow sets how many of the latest calls are considered to compute the failure rate and how many
+// calls, at least, must be made before the circuit breaker can be opened.
+func CircuitBreakerWindow(size, minCalls int) CircuitBreakerOption {
+	return func(o *circuitBreakerOptions) error {
+		if size < 1 {
+			return fmt.Errorf("window size must be greater than 0")
+		}
+		if minCalls < 1 || minCalls > size {
+			return fmt.Errorf("minCalls must be between 1 and the window size")
+		}
+		o.windowSize = size
+		o.minCalls = minCalls
+		return nil
+	}
+}
+
+// CircuitBreakerOpenTimeout sets how long the circuit breaker stays open before letting trial calls through.
+func CircuitBreakerOpenTimeout(d time.Duration) CircuitBreakerOption {
+	return func(o *circuitBreakerOptions) error {
+		if d <= 0 {
+			return fmt.Errorf("openTimeout must be greater than 0")
+		}
+		o.openTimeout = d
+		return nil
+	}
+}
+
+// CircuitBreakerHalfOpenCalls sets how many trial calls must succeed, when half-open, to close the circuit breaker.
+func CircuitBreakerHalfOpenCalls(n int) CircuitBreakerOption {
+	return func(o *circuitBreakerOptions) error {
+		if n < 1 {
+			return fmt.Errorf("halfOpenCalls must be greater than 0")
+		}
+		o.halfOpenCalls = n
+		return nil
+	}
+}
+
+// CircuitBreakerIsFailure sets the function that decides whether an error counts as a failure. By default, every error does.
+func CircuitBreakerIsFailure(isFailure func(error) bool) CircuitBreakerOption {
+	return func(o *circuitBreakerOptions) error {
+		if isFailure == nil {
+			return fmt.Errorf("isFailure function must not be nil")
+		}
+		o.isFailure = isFailure
+		return nil
+	}
+}
+
+// CircuitBreakerOnStateChange sets a function called on every state transition, for example to log it.
+// It's called synchronously by the goroutine that caused the transition.
+func CircuitBreakerOnStateChange(f func(from, to CircuitState)) CircuitBreakerOption {
+	return func(o *circuitBreakerOptions) error {
+		if f == nil {
+			return fmt.Errorf("onStateChange function must not be nil")
+		}
+		o.onStateChange = f
+		return nil
+	}
+}
+
+// CircuitBreakerClock sets the Clock of the circuit breaker. It defaults to RealClock.
+func CircuitBreakerClock(c Clock) CircuitBreakerOption {
+	return func(o *circuitBreakerOptions) error {
+		if c == nil {
+			return fmt.Errorf("clock must not be nil")
+		}
+		o.clock = c
+		return nil
+	}
+}
+
+func newDefaultCircuitBreakerOptions() *circuitBreakerOptions {
+	return &circuitBreakerOptions{
+		failureRate:   0.5,
+		windowSize:    20,
+		minCalls:      10,
+		openTimeout:   30 * time.Second,
+		halfOpenCalls: 1,
+		isFailure:     func(error) bool { return true },
+		onStateChange: nil,
+		clock:         RealClock(),
+	}
+}
+
+func applyCircuitBreakerOptions(opt []CircuitBreakerOption) (*circuitBreakerOptions, error) {
+	opts := newDefaultCircuitBreakerOptions()
+	for _, o := range opt {
+		if err := o(opts); err != nil {
+			return opts, err
+		}
+	}
+	return opts, nil
+}
+
+func assertCircuitBreakerOptions(opt []CircuitBreakerOption) *circuitBreakerOptions {
+	opts, err := applyCircuitBreakerOptions(opt)
+	if err != nil {
+		panic(fmt.Errorf("invalid circuit breaker options: %v", err))
+	}
+	return opts
+}
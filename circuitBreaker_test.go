@@ -0,0 +1,349 @@
+package rivo_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/agiac/rivo"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func ExampleCircuitBreak() {
+	ctx := context.Background()
+
+	cb := NewCircuitBreaker(
+		CircuitBreakerWindow(4, 2),
+		CircuitBreakerOnStateChange(func(from, to CircuitState) {
+			fmt.Printf("circuit %s -> %s\n", from, to)
+		}),
+	)
+
+	// send fails for every item, as if the service were down
+	send := func(ctx context.Context, n int) (None, error) {
+		return None{}, fmt.Errorf("failed to send %d", n)
+	}
+
+	p := Pipe(Of(1, 2, 3, 4), CircuitBreak(cb, send, CircuitFallbackDrop[int, None]()))
+
+	err := Run(ctx, p)
+
+	fmt.Println(err)
+
+	// Output:
+	// circuit closed -> open
+	// failed to send 1
+	// failed to send 2
+}
+
+func TestCircuitBreak(t *testing.T) {
+	errDown := errors.New("service down")
+
+	// service returns a function that fails while down is true
+	service := func(down *atomic.Bool) func(context.Context, int) (int, error) {
+		return func(ctx context.Context, n int) (int, error) {
+			if down.Load() {
+				return 0, errDown
+			}
+			return n, nil
+		}
+	}
+
+	// send sends an item to the pipeline and returns its output
+	send := func(in chan<- int, out <-chan int, n int) int {
+		in <- n
+		return <-out
+	}
+
+	t.Run("open on failure rate", func(t *testing.T) {
+		ctx := context.Background()
+
+		var down atomic.Bool
+		cb := NewCircuitBreaker(CircuitBreakerWindow(4, 4), CircuitBreakerFailureRate(0.5))
+
+		in := make(chan int)
+		errs := make(chan error)
+		out := CircuitBreak(cb, service(&down), CircuitFallbackDefault[int](-1))(ctx, in, errs)
+
+		assert.Equal(t, 1, send(in, out, 1))
+		assert.Equal(t, 2, send(in, out, 2))
+		assert.Equal(t, 3, send(in, out, 3))
+
+		down.Store(true)
+
+		in <- 4
+		assert.ErrorIs(t, <-errs, errDown)
+		assert.Equal(t, CircuitClosed, cb.State())
+
+		// 2 failures out of the latest 4 calls
+		in <- 5
+		assert.ErrorIs(t, <-errs, errDown)
+		assert.Equal(t, CircuitOpen, cb.State())
+
+		assert.Equal(t, -1, send(in, out, 6))
+
+		close(in)
+		assert.Empty(t, Collect(out))
+	})
+
+	t.Run("half open after timeout", func(t *testing.T) {
+		ctx := context.Background()
+
+		clock := NewFakeClock(time.Now())
+
+		var mu sync.Mutex
+		var transitions []string
+
+		cb := NewCircuitBreaker(
+			CircuitBreakerWindow(1, 1),
+			CircuitBreakerOpenTimeout(time.Minute),
+			CircuitBreakerHalfOpenCalls(2),
+			CircuitBreakerClock(clock),
+			CircuitBreakerOnStateChange(func(from, to CircuitState) {
+				mu.Lock()
+				defer mu.Unlock()
+				transitions = append(transitions, fmt.Sprintf("%s->%s", from, to))
+			}),
+		)
+
+		var down atomic.Bool
+		down.Store(true)
+
+		in := make(chan int)
+		errs := make(chan error)
+		out := CircuitBreak(cb, service(&down), CircuitFallbackDefault[int](-1))(ctx, in, errs)
+
+		in <- 1
+		assert.ErrorIs(t, <-errs, errDown)
+		assert.Equal(t, CircuitOpen, cb.State())
+		assert.Equal(t, -1, send(in, out, 2))
+
+		// The first trial fails, so the circuit is opened again
+		clock.Advance(time.Minute)
+		assert.Equal(t, CircuitHalfOpen, cb.State())
+		in <- 3
+		assert.ErrorIs(t, <-errs, errDown)
+		assert.Equal(t, CircuitOpen, cb.State())
+
+		// Both trials succeed, so the circuit is closed
+		down.Store(false)
+		clock.Advance(time.Minute)
+		assert.Equal(t, 4, send(in, out, 4))
+		assert.Equal(t, CircuitHalfOpen, cb.State())
+		assert.Equal(t, 5, send(in, out, 5))
+		assert.Equal(t, CircuitClosed, cb.State())
+
+		close(in)
+		Collect(out)
+
+		mu.Lock()
+		defer mu.Unlock()
+		assert.Equal(t, []string{
+			"closed->open",
+			"open->half-open",
+			"half-open->open",
+			"open->half-open",
+			"half-open->closed",
+		}, transitions)
+	})
+
+	t.Run("trial calls that panic or time out are failures", func(t *testing.T) {
+		ctx := context.Background()
+
+		clock := NewFakeClock(time.Now())
+
+		cb := NewCircuitBreaker(CircuitBreakerWindow(1, 1), CircuitBreakerOpenTimeout(time.Minute), CircuitBreakerClock(clock))
+
+		f := func(ctx context.Context, n int) (int, error) {
+			switch n {
+			case 1:
+				return 0, errDown
+			case 2:
+				panic("trial")
+			case 3:
+				// Ignore the deadline and succeed
+				<-ctx.Done()
+				return n, nil
+			default:
+				return n, nil
+			}
+		}
+
+		in := make(chan int)
+		errs := make(chan error)
+		out := CircuitBreak(cb, f, CircuitFallbackDefault[int](-1), ForEachOutputRecover(), ForEachOutputItemTimeout(10*time.Millisecond))(ctx, in, errs)
+
+		in <- 1
+		assert.ErrorIs(t, <-errs, errDown)
+		assert.Equal(t, CircuitOpen, cb.State())
+
+		clock.Advance(time.Minute)
+		in <- 2
+		var panicErr *PanicError
+		assert.ErrorAs(t, <-errs, &panicErr)
+		assert.Equal(t, CircuitOpen, cb.State())
+
+		clock.Advance(time.Minute)
+		in <- 3
+		var timeoutErr *TimeoutError
+		assert.ErrorAs(t, <-errs, &timeoutErr)
+		assert.Equal(t, CircuitOpen, cb.State())
+
+		clock.Advance(time.Minute)
+		assert.Equal(t, 4, send(in, out, 4))
+		assert.Equal(t, CircuitClosed, cb.State())
+
+		close(in)
+		assert.Empty(t, Collect(out))
+	})
+
+	t.Run("calls allowed while closed are not trial calls", func(t *testing.T) {
+		ctx := context.Background()
+
+		clock := NewFakeClock(time.Now())
+
+		cb := NewCircuitBreaker(CircuitBreakerWindow(1, 1), CircuitBreakerOpenTimeout(time.Minute), CircuitBreakerClock(clock))
+
+		started := make(chan int)
+		gates := map[int]chan struct{}{1: make(chan struct{}), 3: make(chan struct{})}
+
+		// 2 fails, while 1 and 3 succeed once released
+		f := func(ctx context.Context, n int) (int, error) {
+			started <- n
+			if n == 2 {
+				return 0, errDown
+			}
+			<-gates[n]
+			return n, nil
+		}
+
+		in := make(chan int)
+		errs := make(chan error)
+		out := CircuitBreak(cb, f, CircuitFallbackDefault[int](-1), ForEachOutputPoolSize(2))(ctx, in, errs)
+
+		in <- 1
+		assert.Equal(t, 1, <-started)
+
+		in <- 2
+		assert.Equal(t, 2, <-started)
+		assert.ErrorIs(t, <-errs, errDown)
+		assert.Equal(t, CircuitOpen, cb.State())
+
+		clock.Advance(time.Minute)
+
+		in <- 3
+		assert.Equal(t, 3, <-started)
+
+		// 1 was allowed while closed, so it doesn't close the circuit
+		close(gates[1])
+		assert.Equal(t, 1, <-out)
+		assert.Equal(t, CircuitHalfOpen, cb.State())
+
+		close(gates[3])
+		assert.Equal(t, 3, <-out)
+		assert.Equal(t, CircuitClosed, cb.State())
+
+		close(in)
+		assert.Empty(t, Collect(out))
+	})
+
+	t.Run("with on before close", func(t *testing.T) {
+		ctx := context.Background()
+
+		var down atomic.Bool
+		down.Store(true)
+		cb := NewCircuitBreaker(CircuitBreakerWindow(1, 1))
+
+		rejected, fallback := CircuitFallbackRoute[int, int]()
+
+		var got []int
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			got = Collect(rejected)
+		}()
+
+		var closed atomic.Bool
+		p := CircuitBreak(cb, service(&down), fallback, ForEachOutputOnBeforeClose(func(ctx context.Context) {
+			closed.Store(true)
+		}))
+
+		_, err := RunCollect(ctx, Pipe(Of(1, 2), p))
+
+		assert.ErrorIs(t, err, errDown)
+		assert.True(t, closed.Load())
+
+		<-done
+		assert.Equal(t, []int{2}, got)
+	})
+
+	t.Run("with error fallback", func(t *testing.T) {
+		ctx := context.Background()
+
+		var down atomic.Bool
+		down.Store(true)
+		cb := NewCircuitBreaker(CircuitBreakerWindow(1, 1))
+
+		_, err := RunCollect(ctx, Pipe(Of(1, 2), CircuitBreak(cb, service(&down), CircuitFallback[int, int]{})))
+
+		var itemErr *ItemError
+		if assert.ErrorAs(t, err, &itemErr) {
+			assert.Equal(t, 2, itemErr.Item)
+		}
+		assert.ErrorIs(t, err, errDown)
+		assert.ErrorIs(t, err, ErrCircuitOpen)
+	})
+
+	t.Run("with route fallback", func(t *testing.T) {
+		ctx := context.Background()
+
+		var down atomic.Bool
+		down.Store(true)
+		cb := NewCircuitBreaker(CircuitBreakerWindow(1, 1))
+
+		rejected, fallback := CircuitFallbackRoute[int, int]()
+
+		var got []int
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			got = Collect(rejected)
+		}()
+
+		_, err := RunCollect(ctx, Pipe(Of(1, 2, 3), CircuitBreak(cb, service(&down), fallback)))
+
+		assert.ErrorIs(t, err, errDown)
+
+		<-done
+		assert.Equal(t, []int{2, 3}, got)
+	})
+
+	t.Run("with failure classifier", func(t *testing.T) {
+		ctx := context.Background()
+
+		var down atomic.Bool
+		down.Store(true)
+		cb := NewCircuitBreaker(CircuitBreakerWindow(1, 1), CircuitBreakerIsFailure(func(err error) bool {
+			return !errors.Is(err, errDown)
+		}))
+
+		_, err := RunCollect(ctx, Pipe(Of(1, 2, 3), CircuitBreak(cb, service(&down), CircuitFallbackDrop[int, int]())))
+
+		assert.EqualError(t, err, "service down\nservice down\nservice down")
+
+		assert.Equal(t, CircuitClosed, cb.State())
+	})
+
+	t.Run("panics", func(t *testing.T) {
+		assert.Panics(t, func() { NewCircuitBreaker(CircuitBreakerFailureRate(0)) })
+		assert.Panics(t, func() { NewCircuitBreaker(CircuitBreakerWindow(5, 6)) })
+		assert.Panics(t, func() { NewCircuitBreaker(CircuitBreakerOpenTimeout(0)) })
+		assert.Panics(t, func() { NewCircuitBreaker(CircuitBreakerHalfOpenCalls(0)) })
+		assert.Panics(t, func() { NewCircuitBreaker(CircuitBreakerOnStateChange(nil)) })
+	})
+}
@@ -0,0 +1,278 @@
+package rivo
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Clock is the source of time of the time-based pipelines, such as Debounce, ThrottleFirst, ThrottleLast and Sample.
+// Use RealClock, the default, to follow the system time and a FakeClock to control the time in tests.
+type Clock interface {
+	Now() time.Time
+	NewTimer(d time.Duration) Timer
+	NewTicker(d time.Duration) Ticker
+}
+
+// Timer is the Clock equivalent of time.Timer.
+type Timer interface {
+	C() <-chan time.Time
+	Stop() bool
+	Reset(d time.Duration) bool
+}
+
+// Ticker is the Clock equivalent of time.Ticker.
+type Ticker interface {
+	C() <-chan time.Time
+	Stop()
+}
+
+// RealClock returns a Clock backed by the time package.
+func RealClock() Clock {
+	return realClock{}
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) NewTimer(d time.Duration) Timer {
+	return realTimer{time.NewTimer(d)}
+}
+
+func (realClock) NewTicker(d time.Duration) Ticker {
+	return realTicker{time.NewTicker(d)}
+}
+
+type realTimer struct {
+	*time.Timer
+}
+
+func (t realTimer) C() <-chan time.Time {
+	return t.Timer.C
+}
+
+type realTicker struct {
+	*time.Ticker
+}
+
+func (t realTicker) C() <-chan time.Time {
+	return t.Ticker.C
+}
+
+// FakeClock is a Clock whose time only moves when Advance is called. It's safe for concurrent use.
+type FakeClock struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	now     time.Time
+	waiters []*fakeWaiter
+}
+
+// NewFakeClock returns a FakeClock set to the given time.
+func NewFakeClock(now time.Time) *FakeClock {
+	c := &FakeClock{now: now}
+	c.cond = sync.NewCond(&c.mu)
+	return c
+}
+
+// Now returns the current time of the clock.
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// NewTimer returns a Timer that fires once the clock has been advanced by d.
+func (c *FakeClock) NewTimer(d time.Duration) Timer {
+	w := &fakeWaiter{clock: c, ch: make(chan time.Time, 1)}
+	w.Reset(d)
+	return w
+}
+
+// NewTicker returns a Ticker that fires every time the clock has been advanced by d. It panics if d is not positive.
+func (c *FakeClock) NewTicker(d time.Duration) Ticker {
+	if d <= 0 {
+		panic("d must be greater than 0")
+	}
+
+	w := &fakeWaiter{clock: c, ch: make(chan time.Time, 1), period: d}
+	w.Reset(d)
+	return fakeTicker{w}
+}
+
+// Advance moves the time of the clock forward by d, firing, in order, the timers and tickers that expire meanwhile.
+// As with the time package, a ticker whose previous tick has not been received yet drops the following ones.
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	target := c.now.Add(d)
+
+	for {
+		var next *fakeWaiter
+		for _, w := range c.waiters {
+			if !w.when.After(target) && (next == nil || w.when.Before(next.when)) {
+				next = w
+			}
+		}
+
+		if next == nil {
+			break
+		}
+
+		c.now = next.when
+
+		select {
+		case next.ch <- next.when:
+		default:
+		}
+
+		if next.period > 0 {
+			next.when = next.when.Add(next.period)
+		} else {
+			c.remove(next)
+		}
+	}
+
+	c.now = target
+}
+
+// BlockUntil blocks until at least n timers and tickers are waiting to fire.
+// It's useful to make sure that a pipeline running in another goroutine has set its timers before advancing the clock.
+func (c *FakeClock) BlockUntil(n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for len(c.waiters) < n {
+		c.cond.Wait()
+	}
+}
+
+func (c *FakeClock) remove(w *fakeWaiter) bool {
+	for i, other := range c.waiters {
+		if other == w {
+			c.waiters = append(c.waiters[:i], c.waiters[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// fakeWaiter is a timer or, if period is greater than 0, a ticker of a FakeClock.
+type fakeWaiter struct {
+	clock  *FakeClock
+	ch     chan time.Time
+	when   time.Time
+	period time.Duration
+}
+
+func (w *fakeWaiter) C() <-chan time.Time {
+	return w.ch
+}
+
+func (w *fakeWaiter) Stop() bool {
+	w.clock.mu.Lock()
+	defer w.clock.mu.Unlock()
+
+	w.drain()
+
+	return w.clock.remove(w)
+}
+
+func (w *fakeWaiter) Reset(d time.Duration) bool {
+	w.clock.mu.Lock()
+	defer w.clock.mu.Unlock()
+
+	w.drain()
+
+	active := w.clock.remove(w)
+
+	w.when = w.clock.now.Add(d)
+
+	if d <= 0 && w.period == 0 {
+		w.ch <- w.when
+		return active
+	}
+
+	w.clock.waiters = append(w.clock.waiters, w)
+	w.clock.cond.Broadcast()
+
+	return active
+}
+
+// drain discards a value not received yet, as the time package does since Go 1.23.
+func (w *fakeWaiter) drain() {
+	select {
+	case <-w.ch:
+	default:
+	}
+}
+
+type fakeTicker struct {
+	w *fakeWaiter
+}
+
+func (t fakeTicker) C() <-chan time.Time {
+	return t.w.C()
+}
+
+func (t fakeTicker) Stop() {
+	t.w.Stop()
+}
+
+type timeOptions struct {
+	clock      Clock
+	bufferSize int
+}
+
+// TimeOption configures the time-based pipelines Debounce, ThrottleFirst, ThrottleLast and Sample.
+type TimeOption func(*timeOptions) error
+
+// TimeClock sets the Clock of a time-based pipeline. It defaults to RealClock.
+func TimeClock(c Clock) TimeOption {
+	return func(o *timeOptions) error {
+		if c == nil {
+			return fmt.Errorf("clock must not be nil")
+		}
+		o.clock = c
+		return nil
+	}
+}
+
+// TimeBufferSize sets the buffer size of the output stream of a time-based pipeline.
+func TimeBufferSize(n int) TimeOption {
+	return func(o *timeOptions) error {
+		if n < 0 {
+			return fmt.Errorf("bufferSize must be greater than or equal to 0")
+		}
+		o.bufferSize = n
+		return nil
+	}
+}
+
+func newDefaultTimeOptions() *timeOptions {
+	return &timeOptions{
+		clock:      RealClock(),
+		bufferSize: 0,
+	}
+}
+
+func applyTimeOptions(opt []TimeOption) (*timeOptions, error) {
+	opts := newDefaultTimeOptions()
+	for _, o := range opt {
+		if err := o(opts); err != nil {
+			return opts, err
+		}
+	}
+	return opts, nil
+}
+
+func assertTimeOptions(opt []TimeOption) *timeOptions {
+	opts, err := applyTimeOptions(opt)
+	if err != nil {
+		panic(fmt.Errorf("invalid time options: %v", err))
+	}
+	return opts
+}
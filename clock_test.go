@@ -0,0 +1,88 @@
+package rivo_test
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/agiac/rivo"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFakeClock(t *testing.T) {
+	t0 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("advance time", func(t *testing.T) {
+		c := NewFakeClock(t0)
+
+		c.Advance(time.Minute)
+
+		assert.Equal(t, t0.Add(time.Minute), c.Now())
+	})
+
+	t.Run("fire timers", func(t *testing.T) {
+		c := NewFakeClock(t0)
+
+		timer := c.NewTimer(time.Second)
+
+		c.Advance(500 * time.Millisecond)
+
+		select {
+		case <-timer.C():
+			assert.Fail(t, "timer should not fire yet")
+		default:
+		}
+
+		c.Advance(time.Second)
+
+		assert.Equal(t, t0.Add(time.Second), <-timer.C())
+		assert.False(t, timer.Stop())
+	})
+
+	t.Run("stop and reset timers", func(t *testing.T) {
+		c := NewFakeClock(t0)
+
+		timer := c.NewTimer(time.Second)
+
+		assert.True(t, timer.Stop())
+
+		c.Advance(time.Second)
+
+		assert.False(t, timer.Reset(time.Second))
+
+		c.Advance(time.Second)
+
+		assert.Equal(t, t0.Add(2*time.Second), <-timer.C())
+	})
+
+	t.Run("fire tickers", func(t *testing.T) {
+		c := NewFakeClock(t0)
+
+		ticker := c.NewTicker(time.Second)
+		defer ticker.Stop()
+
+		c.Advance(time.Second)
+		assert.Equal(t, t0.Add(time.Second), <-ticker.C())
+
+		// The ticks that are not received are dropped
+		c.Advance(3 * time.Second)
+		assert.Equal(t, t0.Add(2*time.Second), <-ticker.C())
+
+		select {
+		case <-ticker.C():
+			assert.Fail(t, "ticks should be dropped")
+		default:
+		}
+	})
+
+	t.Run("block until timers are set", func(t *testing.T) {
+		c := NewFakeClock(t0)
+
+		go func() {
+			time.Sleep(10 * time.Millisecond)
+			c.NewTimer(time.Second)
+		}()
+
+		c.BlockUntil(1)
+	})
+}
@@ -10,18 +10,31 @@ func Connect[T any](pp ...Sync[T]) Sync[T] {
 		out := make(chan None)
 
 		go func() {
+			labelGoroutine(ctx, "Connect", -1)
+
 			defer close(out)
 
 			inS := TeeStreamN(ctx, in, len(pp))
 
+			outs := make([]Stream[None], len(pp))
+			described := make([]any, len(pp))
+			for i, p := range pp {
+				outs[i] = p(ctx, inS[i], errs)
+				described[i] = outs[i]
+			}
+
+			describeStage(ctx, TopologyNode{Kind: "Connect"}, described, []any{Stream[None](out)})
+
 			wg := sync.WaitGroup{}
 			wg.Add(len(pp))
 
-			for i, p := range pp {
-				go func(i int, p Sync[T]) {
+			for i, s := range outs {
+				go func() {
+					labelGoroutine(ctx, "Connect", i)
+
 					defer wg.Done()
-					<-p(ctx, inS[i], errs)
-				}(i, p)
+					<-s
+				}()
 			}
 
 			wg.Wait()
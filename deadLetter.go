@@ -0,0 +1,102 @@
+package rivo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+)
+
+// DeadLetter is an item that a stage failed to process, together with the error it failed with.
+type DeadLetter[T any] struct {
+	Item T
+	Err  error
+}
+
+// MarshalJSON encodes the dead letter as an object with the item and the error message,
+// so that dead letters can be written as JSON lines and replayed later.
+func (d DeadLetter[T]) MarshalJSON() ([]byte, error) {
+	var msg string
+	if d.Err != nil {
+		msg = d.Err.Error()
+	}
+
+	return json.Marshal(struct {
+		Item  T      `json:"item"`
+		Error string `json:"error"`
+	}{d.Item, msg})
+}
+
+type deadLettersKey struct{}
+
+// withItemErrors reports whether the stages must attach the failed items to their errors. See WithDeadLetters.
+func withItemErrors(ctx context.Context) bool {
+	v, _ := ctx.Value(deadLettersKey{}).(bool)
+	return v
+}
+
+// WithDeadLetters returns a pipeline that runs p and routes the items it fails to process, together with their
+// errors, to the given sink as DeadLetters, instead of sending the errors to the error channel.
+// The operators based on ForEachOutput, such as Map, Filter or Do, attach the failed item to their errors, as do
+// ItemError, StageError, TimeoutError and PanicError. Only the failed items of type T are routed to the sink, e.g.
+// those of the first stage of p; the other errors are forwarded to the error channel.
+// The sink can be any pipeline, e.g. csv.ToWriter after a Map, and its output is discarded. It's run with the same
+// context and error channel as p and the output stream of the pipeline is closed only after the sink is done.
+func WithDeadLetters[T, U, V any](sink Pipeline[DeadLetter[T], V], p Pipeline[T, U]) Pipeline[T, U] {
+	return func(ctx context.Context, in Stream[T], errs chan<- error) Stream[U] {
+		ctx = context.WithValue(ctx, deadLettersKey{}, true)
+
+		letters := make(chan DeadLetter[T])
+		describeStage(ctx, TopologyNode{Kind: "DeadLetters"}, nil, []any{Stream[DeadLetter[T]](letters)})
+		sinkOut := sink(ctx, letters, errs)
+
+		return interceptErrors(ctx, func(pErrs chan<- error) Stream[U] {
+			return p(ctx, in, pErrs)
+		}, func(err error) {
+			if item, ok := failedItem(err).(T); ok {
+				select {
+				case <-ctx.Done():
+				case letters <- DeadLetter[T]{Item: item, Err: err}:
+				}
+				return
+			}
+
+			if errs == nil {
+				return
+			}
+
+			select {
+			case <-ctx.Done():
+			case errs <- err:
+			}
+		}, func() {
+			close(letters)
+			for range sinkOut {
+			}
+		})
+	}
+}
+
+// failedItem returns the item carried by the error, if any.
+func failedItem(err error) any {
+	var stageErr *StageError
+	if errors.As(err, &stageErr) && stageErr.Index >= 0 {
+		return stageErr.Item
+	}
+
+	var itemErr *ItemError
+	if errors.As(err, &itemErr) {
+		return itemErr.Item
+	}
+
+	var timeoutErr *TimeoutError
+	if errors.As(err, &timeoutErr) {
+		return timeoutErr.Item
+	}
+
+	var panicErr *PanicError
+	if errors.As(err, &panicErr) {
+		return panicErr.Item
+	}
+
+	return nil
+}
@@ -0,0 +1,170 @@
+package rivo_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	. "github.com/agiac/rivo"
+	rcsv "github.com/agiac/rivo/csv"
+	rio "github.com/agiac/rivo/io"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func ExampleWithDeadLetters() {
+	ctx := context.Background()
+
+	parse := Map(func(ctx context.Context, s string) (int, error) {
+		return strconv.Atoi(s)
+	})
+
+	// Write the dead letters as JSON lines
+	toJSON := Map(func(ctx context.Context, d DeadLetter[string]) ([]byte, error) {
+		b, err := json.Marshal(d)
+		return append(b, '\n'), err
+	})
+
+	deadLetters := Pipe(toJSON, rio.ToWriter(os.Stdout))
+
+	got, err := RunCollect(ctx, Pipe(Of("1", "two", "3"), WithDeadLetters(deadLetters, parse)))
+
+	fmt.Println(got, err)
+
+	// Output:
+	// {"item":"two","error":"strconv.Atoi: parsing \"two\": invalid syntax"}
+	// [1 3] <nil>
+}
+
+func TestWithDeadLetters(t *testing.T) {
+	errOdd := errors.New("odd")
+
+	failOdd := func(ctx context.Context, n int) (int, error) {
+		if n%2 == 1 {
+			return 0, errOdd
+		}
+		return n, nil
+	}
+
+	// collect returns a sink that appends the dead letters to the given slice
+	collect := func(letters *[]DeadLetter[int]) Sync[DeadLetter[int]] {
+		return Do(func(ctx context.Context, d DeadLetter[int]) error {
+			*letters = append(*letters, d)
+			return nil
+		})
+	}
+
+	t.Run("route failed items", func(t *testing.T) {
+		ctx := context.Background()
+
+		var letters []DeadLetter[int]
+
+		got, err := RunCollect(ctx, Pipe(Of(1, 2, 3, 4), WithDeadLetters(collect(&letters), Map(failOdd))))
+
+		assert.NoError(t, err)
+		assert.Equal(t, []int{2, 4}, got)
+		if assert.Len(t, letters, 2) {
+			assert.Equal(t, 1, letters[0].Item)
+			assert.ErrorIs(t, letters[0].Err, errOdd)
+			assert.Equal(t, 3, letters[1].Item)
+		}
+	})
+
+	t.Run("with other operators", func(t *testing.T) {
+		ctx := context.Background()
+
+		var letters []DeadLetter[int]
+
+		f := Filter(func(ctx context.Context, n int) (bool, error) {
+			_, err := failOdd(ctx, n)
+			return true, err
+		})
+
+		d := Do(func(ctx context.Context, n int) error {
+			if n == 4 {
+				<-ctx.Done()
+				return ctx.Err()
+			}
+			return nil
+		}, DoItemTimeout(10*time.Millisecond))
+
+		err := Run(ctx, Pipe(Of(1, 2, 3, 4), WithDeadLetters(collect(&letters), Pipe(f, d))))
+
+		assert.NoError(t, err)
+		if assert.Len(t, letters, 3) {
+			assert.Equal(t, []int{1, 3, 4}, []int{letters[0].Item, letters[1].Item, letters[2].Item})
+
+			var timeoutErr *TimeoutError
+			assert.ErrorAs(t, letters[2].Err, &timeoutErr)
+		}
+	})
+
+	t.Run("with named stages", func(t *testing.T) {
+		ctx := context.Background()
+
+		var letters []DeadLetter[int]
+
+		_, err := RunCollect(ctx, Pipe(Of(1, 2), WithDeadLetters(collect(&letters), Named("even", Map(failOdd)))))
+
+		assert.NoError(t, err)
+		if assert.Len(t, letters, 1) {
+			assert.EqualError(t, letters[0].Err, "even: item 0: odd")
+		}
+	})
+
+	t.Run("forward other errors", func(t *testing.T) {
+		ctx := context.Background()
+
+		var letters []DeadLetter[int]
+
+		toString := Map(func(ctx context.Context, n int) (string, error) {
+			return strconv.Itoa(n), nil
+		})
+
+		failTwo := Map(func(ctx context.Context, s string) (string, error) {
+			if s == "2" {
+				return "", errors.New("two")
+			}
+			return s, nil
+		})
+
+		got, err := RunCollect(ctx, Pipe(Of(1, 2, 3), WithDeadLetters(collect(&letters), Pipe(toString, failTwo))))
+
+		// The failed item is not of the input type of the pipeline
+		assert.Equal(t, []string{"1", "3"}, got)
+		assert.EqualError(t, err, "two")
+		assert.Empty(t, letters)
+	})
+
+	t.Run("with csv sink", func(t *testing.T) {
+		ctx := context.Background()
+
+		buf := &bytes.Buffer{}
+		w := csv.NewWriter(buf)
+
+		toRecord := Map(func(ctx context.Context, d DeadLetter[int]) ([]string, error) {
+			return []string{strconv.Itoa(d.Item), d.Err.Error()}, nil
+		})
+
+		_, err := RunCollect(ctx, Pipe(Of(1, 2, 3), WithDeadLetters(Pipe(toRecord, rcsv.ToWriter(w)), Map(failOdd))))
+
+		w.Flush()
+
+		assert.NoError(t, err)
+		assert.Equal(t, "1,odd\n3,odd\n", buf.String())
+	})
+
+	t.Run("marshal to json", func(t *testing.T) {
+		b, err := json.Marshal(DeadLetter[int]{Item: 1, Err: errOdd})
+
+		assert.NoError(t, err)
+		assert.JSONEq(t, `{"item":1,"error":"odd"}`, string(b))
+	})
+}
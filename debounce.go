@@ -0,0 +1,71 @@
+package rivo
+
+import (
+	"context"
+	"time"
+)
+
+// Debounce returns a pipeline that emits an item of the input stream only once d has passed without receiving
+// another one, discarding the items that are followed too soon by a newer one.
+// When the input stream is closed, the last item is emitted right away if it's still waiting.
+// Debounce panics if d is not positive or if invalid options are provided.
+func Debounce[T any](d time.Duration, opt ...TimeOption) Pipeline[T, T] {
+	if d <= 0 {
+		panic("d must be greater than 0")
+	}
+
+	o := assertTimeOptions(opt)
+
+	return func(ctx context.Context, in Stream[T], errs chan<- error) Stream[T] {
+		out := make(chan T, o.bufferSize)
+
+		if DescribeStage(ctx, TopologyNode{Kind: "Debounce", BufferSize: o.bufferSize}, in, out) {
+			return out
+		}
+
+		go func() {
+			defer close(out)
+
+			timer := o.clock.NewTimer(d)
+			timer.Stop()
+			defer timer.Stop()
+
+			var last T
+			pending := false
+
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case v, ok := <-in:
+					if !ok {
+						if pending {
+							sendLatest(ctx, out, last)
+						}
+						return
+					}
+
+					last, pending = v, true
+					timer.Reset(d)
+				case <-timer.C():
+					if exit := sendLatest(ctx, out, last); exit {
+						return
+					}
+					pending = false
+				}
+			}
+		}()
+
+		return out
+	}
+}
+
+// sendLatest sends the latest item of a time-based pipeline, returning true if the context is done.
+func sendLatest[T any](ctx context.Context, out chan<- T, v T) (exit bool) {
+	select {
+	case <-ctx.Done():
+		return true
+	case out <- v:
+		return false
+	}
+}
@@ -0,0 +1,97 @@
+package rivo_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	. "github.com/agiac/rivo"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func ExampleDebounce() {
+	ctx := context.Background()
+
+	in := make(chan string)
+
+	go func() {
+		defer close(in)
+		in <- "h"
+		in <- "he"
+		in <- "hello"
+		time.Sleep(100 * time.Millisecond)
+		in <- "hello w"
+		in <- "hello world"
+	}()
+
+	for s := range Debounce[string](50*time.Millisecond)(ctx, in, nil) {
+		fmt.Println(s)
+	}
+
+	// Output:
+	// hello
+	// hello world
+}
+
+func TestDebounce(t *testing.T) {
+	t.Run("emit after quiet period", func(t *testing.T) {
+		ctx := context.Background()
+
+		clock := NewFakeClock(time.Now())
+
+		in := make(chan int)
+		out := Debounce[int](time.Second, TimeClock(clock))(ctx, in, nil)
+
+		in <- 1
+		clock.BlockUntil(1)
+		clock.Advance(time.Second)
+
+		assert.Equal(t, 1, <-out)
+
+		in <- 2
+		in <- 3
+		close(in)
+
+		assert.Equal(t, []int{3}, Collect(out))
+	})
+
+	t.Run("discard items followed by newer ones", func(t *testing.T) {
+		ctx := context.Background()
+
+		in := make(chan int)
+
+		go func() {
+			defer close(in)
+			in <- 1
+			in <- 2
+			in <- 3
+			time.Sleep(150 * time.Millisecond)
+			in <- 4
+			time.Sleep(150 * time.Millisecond)
+		}()
+
+		got := Collect(Debounce[int](50*time.Millisecond)(ctx, in, nil))
+
+		assert.Equal(t, []int{3, 4}, got)
+	})
+
+	t.Run("with context cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+
+		in := make(chan int)
+		out := Debounce[int](time.Hour)(ctx, in, nil)
+
+		in <- 1
+		cancel()
+
+		assert.Empty(t, Collect(out))
+	})
+
+	t.Run("panics", func(t *testing.T) {
+		assert.Panics(t, func() { Debounce[int](0) })
+		assert.Panics(t, func() { Debounce[int](time.Second, TimeClock(nil)) })
+		assert.Panics(t, func() { Debounce[int](time.Second, TimeBufferSize(-1)) })
+	})
+}
@@ -0,0 +1,274 @@
+package rivo
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Topology is the structure of a pipeline, as returned by Describe.
+type Topology struct {
+	Nodes []TopologyNode
+	Edges []TopologyEdge
+}
+
+// TopologyNode is a stage of a pipeline.
+type TopologyNode struct {
+	ID int
+	// Name is the name of the stage set with Named, if any.
+	Name string
+	// Kind is the operator of the stage, e.g. "Map" or "Merge". The input and output streams of the pipeline have
+	// kind "input" and "output", while the stages that can't be described have kind "unknown".
+	Kind string
+	// PoolSize is the number of workers of the stage, or 0 if it doesn't have a pool of workers.
+	PoolSize int
+	// BufferSize is the buffer size of the output stream of the stage.
+	BufferSize int
+}
+
+// TopologyEdge connects the output of a stage to the input of another.
+type TopologyEdge struct {
+	From int
+	To   int
+}
+
+// Describe returns the topology of the given pipeline, with a node for each stage and an edge for each stream
+// connecting two stages.
+// The pipeline is run with a cancelled context, a closed input stream and a nil error channel: the stages of rivo
+// record themselves without processing any item, as do the custom stages using DescribeStage, while the other stages
+// are run and show up as nodes of kind "unknown", so they should stop as soon as their context is done or their input
+// stream is closed.
+// Describing a pipeline has no side effects on its later runs: the functions set with options such as
+// ForEachOutputOnBeforeClose are not called and the side streams, such as those of window.LateItems or
+// CircuitFallbackRoute, are not closed.
+// The stages are described when the pipeline is run, so the pipelines returned by functions such as Tee, that take
+// their context eagerly, are described as generators.
+func Describe[T, U any](p Pipeline[T, U]) Topology {
+	r := &topologyRecorder{producers: make(map[any]int), aliases: make(map[any]any)}
+
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), topologyKey{}, r))
+	cancel()
+
+	var in Stream[T]
+	if _, ok := any(*new(T)).(None); !ok {
+		input := make(chan T)
+		close(input)
+		in = input
+		r.input = in
+	}
+
+	out := p(ctx, in, nil)
+	for range out {
+	}
+
+	if _, ok := any(*new(U)).(None); !ok {
+		r.output = out
+	}
+
+	return r.topology()
+}
+
+// DOT returns the topology in the Graphviz DOT language.
+func (t Topology) DOT() string {
+	var b strings.Builder
+
+	b.WriteString("digraph pipeline {\n\trankdir=LR;\n")
+
+	for _, n := range t.Nodes {
+		fmt.Fprintf(&b, "\tn%d [label=%s];\n", n.ID, strconv.Quote(strings.Join(n.labelLines(), "\n")))
+	}
+
+	for _, e := range t.Edges {
+		fmt.Fprintf(&b, "\tn%d -> n%d;\n", e.From, e.To)
+	}
+
+	b.WriteString("}\n")
+
+	return b.String()
+}
+
+// Mermaid returns the topology as a Mermaid flowchart.
+func (t Topology) Mermaid() string {
+	var b strings.Builder
+
+	b.WriteString("flowchart LR\n")
+
+	for _, n := range t.Nodes {
+		label := strings.ReplaceAll(strings.Join(n.labelLines(), "<br/>"), `"`, "#quot;")
+		fmt.Fprintf(&b, "\tn%d[\"%s\"]\n", n.ID, label)
+	}
+
+	for _, e := range t.Edges {
+		fmt.Fprintf(&b, "\tn%d --> n%d\n", e.From, e.To)
+	}
+
+	return b.String()
+}
+
+func (n TopologyNode) labelLines() []string {
+	switch n.Kind {
+	case "input", "output", "unknown":
+		return []string{n.Kind}
+	}
+
+	title := n.Kind
+	if n.Name != "" {
+		title = fmt.Sprintf("%s (%s)", n.Name, n.Kind)
+	}
+
+	details := fmt.Sprintf("buffer: %d", n.BufferSize)
+	if n.PoolSize > 0 {
+		details = fmt.Sprintf("pool: %d, %s", n.PoolSize, details)
+	}
+
+	return []string{title, details}
+}
+
+type topologyKey struct{}
+
+// topologyRecorder records the stages of a pipeline being described. The streams connecting the stages are
+// identified by their channels, so that the edges can be found by matching the input streams of each stage to the
+// output streams of the others.
+type topologyRecorder struct {
+	mu        sync.Mutex
+	nodes     []TopologyNode
+	inputs    [][]any
+	producers map[any]int
+	aliases   map[any]any
+	input     any
+	output    any
+}
+
+// describeStage records a stage of the pipeline being described, with its input and output streams, and returns
+// true if the pipeline is being described, in which case the stage shouldn't process any item, but only drain its
+// input streams and close its output streams, so that the stages around it are not blocked.
+// The name of the stage is the one set with Named, if any.
+func describeStage(ctx context.Context, node TopologyNode, in []any, out []any) bool {
+	r, ok := ctx.Value(topologyKey{}).(*topologyRecorder)
+	if !ok {
+		return false
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	node.ID = len(r.nodes)
+	node.Name = stageName(ctx)
+
+	r.nodes = append(r.nodes, node)
+	r.inputs = append(r.inputs, in)
+
+	for _, s := range out {
+		r.producers[s] = node.ID
+	}
+
+	return true
+}
+
+// DescribeStage records a custom stage of a pipeline being described with Describe, reading from in and writing to
+// out, and reports whether the pipeline is being described. In that case, it drains in and closes out, so the stage
+// must not process any item and must return out right away. The ID and the Name of the node are set by DescribeStage,
+// the latter to the name set with Named, if any. Generators can pass a nil input stream.
+func DescribeStage[T, U any](ctx context.Context, node TopologyNode, in Stream[T], out chan U) bool {
+	if !describeStage(ctx, node, []any{in}, []any{Stream[U](out)}) {
+		return false
+	}
+
+	go func() {
+		defer close(out)
+		drain(in)
+	}()
+
+	return true
+}
+
+// aliasStream records that the stream to forwards the items of the stream from, in the same order, so that the
+// stages reading from it are connected to the stage writing to from, when the pipeline is being described or traced.
+func aliasStream(ctx context.Context, from, to any) {
+	traceAlias(ctx, from, to)
+
+	r, ok := ctx.Value(topologyKey{}).(*topologyRecorder)
+	if !ok {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.aliases[to] = from
+}
+
+// drain discards the items of the stream until it's closed. A nil stream is considered closed.
+func drain[T any](in Stream[T]) {
+	if in == nil {
+		return
+	}
+
+	for range in {
+	}
+}
+
+func (r *topologyRecorder) topology() Topology {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	t := Topology{Nodes: r.nodes}
+
+	addNode := func(kind string) int {
+		id := len(t.Nodes)
+		t.Nodes = append(t.Nodes, TopologyNode{ID: id, Kind: kind})
+		return id
+	}
+
+	inputID := -1
+	unknown := make(map[any]int)
+
+	producer := func(s any) int {
+		for {
+			from, ok := r.aliases[s]
+			if !ok {
+				break
+			}
+			s = from
+		}
+
+		if id, ok := r.producers[s]; ok {
+			return id
+		}
+
+		if s == r.input {
+			if inputID < 0 {
+				inputID = addNode("input")
+			}
+			return inputID
+		}
+
+		if id, ok := unknown[s]; ok {
+			return id
+		}
+
+		id := addNode("unknown")
+		unknown[s] = id
+
+		return id
+	}
+
+	for id, in := range r.inputs {
+		for _, s := range in {
+			if s == nil || reflect.ValueOf(s).IsNil() {
+				continue
+			}
+			t.Edges = append(t.Edges, TopologyEdge{From: producer(s), To: id})
+		}
+	}
+
+	if r.output != nil {
+		from := producer(r.output)
+		t.Edges = append(t.Edges, TopologyEdge{From: from, To: addNode("output")})
+	}
+
+	return t
+}
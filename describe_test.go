@@ -0,0 +1,348 @@
+package rivo_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/agiac/rivo"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func ExampleDescribe() {
+	parse := Named("parse", Map(func(ctx context.Context, s string) (int, error) {
+		return strconv.Atoi(s)
+	}, MapPoolSize(4), MapBufferSize(10)))
+
+	even := Filter(func(ctx context.Context, n int) (bool, error) {
+		return n%2 == 0, nil
+	})
+
+	p := Pipe3(parse, even, Batch[int](100))
+
+	fmt.Print(Describe(p).Mermaid())
+
+	// Output:
+	// flowchart LR
+	// 	n0["parse (Map)<br/>pool: 4, buffer: 10"]
+	// 	n1["Filter<br/>pool: 1, buffer: 0"]
+	// 	n2["Batch<br/>buffer: 0"]
+	// 	n3["input"]
+	// 	n4["output"]
+	// 	n3 --> n0
+	// 	n0 --> n1
+	// 	n1 --> n2
+	// 	n2 --> n4
+}
+
+func TestDescribe(t *testing.T) {
+	double := Map(func(ctx context.Context, n int) (int, error) {
+		return n * 2, nil
+	})
+
+	discard := Do(func(ctx context.Context, n int) error {
+		return nil
+	}, DoPoolSize(2))
+
+	t.Run("pipes", func(t *testing.T) {
+		p := Pipe3(Of(1, 2, 3), Named("double", double), discard)
+
+		got := Describe(p)
+
+		assert.Equal(t, Topology{
+			Nodes: []TopologyNode{
+				{ID: 0, Kind: "Of"},
+				{ID: 1, Name: "double", Kind: "Map", PoolSize: 1},
+				{ID: 2, Kind: "Do", PoolSize: 2},
+			},
+			Edges: []TopologyEdge{
+				{From: 0, To: 1},
+				{From: 1, To: 2},
+			},
+		}, got)
+
+		assert.Equal(t, `digraph pipeline {
+	rankdir=LR;
+	n0 [label="Of\nbuffer: 0"];
+	n1 [label="double (Map)\npool: 1, buffer: 0"];
+	n2 [label="Do\npool: 2, buffer: 0"];
+	n0 -> n1;
+	n1 -> n2;
+}
+`, got.DOT())
+	})
+
+	t.Run("connect", func(t *testing.T) {
+		p := Pipe(FromFunc(func(ctx context.Context) (int, bool, error) {
+			return 0, false, nil
+		}, FromFuncPoolSize(3)), Connect(discard, Pipe(double, discard)))
+
+		got := Describe(p)
+
+		assert.Equal(t, Topology{
+			Nodes: []TopologyNode{
+				{ID: 0, Kind: "FromFunc", PoolSize: 3},
+				{ID: 1, Kind: "Tee"},
+				{ID: 2, Kind: "Do", PoolSize: 2},
+				{ID: 3, Kind: "Map", PoolSize: 1},
+				{ID: 4, Kind: "Do", PoolSize: 2},
+				{ID: 5, Kind: "Connect"},
+			},
+			Edges: []TopologyEdge{
+				{From: 0, To: 1},
+				{From: 1, To: 2},
+				{From: 1, To: 3},
+				{From: 3, To: 4},
+				{From: 2, To: 5},
+				{From: 4, To: 5},
+			},
+		}, got)
+	})
+
+	t.Run("graph", func(t *testing.T) {
+		g := NewGraph()
+
+		a := AddNode(g, "a", Of(1))
+		b := AddNode(g, "b", Of(2))
+		d := AddNode(g, "double", double)
+		c1 := AddNode(g, "discard 1", discard)
+		c2 := AddNode(g, "discard 2", discard)
+
+		Edge(a, d)
+		Edge(b, d)
+		Edge(d, c1)
+		Edge(d, c2)
+
+		p, err := g.Build()
+		assert.NoError(t, err)
+
+		got := Describe(p)
+
+		assert.Equal(t, Topology{
+			Nodes: []TopologyNode{
+				{ID: 0, Name: "a", Kind: "Of"},
+				{ID: 1, Name: "b", Kind: "Of"},
+				{ID: 2, Kind: "Merge"},
+				{ID: 3, Name: "double", Kind: "Map", PoolSize: 1},
+				{ID: 4, Kind: "Tee"},
+				{ID: 5, Name: "discard 1", Kind: "Do", PoolSize: 2},
+				{ID: 6, Name: "discard 2", Kind: "Do", PoolSize: 2},
+			},
+			Edges: []TopologyEdge{
+				{From: 0, To: 2},
+				{From: 1, To: 2},
+				{From: 2, To: 3},
+				{From: 3, To: 4},
+				{From: 4, To: 5},
+				{From: 4, To: 6},
+			},
+		}, got)
+	})
+
+	t.Run("wrappers and unknown stages", func(t *testing.T) {
+		custom := func(ctx context.Context, in Stream[int], errs chan<- error) Stream[int] {
+			out := make(chan int)
+			go func() {
+				defer close(out)
+				for v := range in {
+					out <- v
+				}
+			}()
+			return out
+		}
+
+		p := Pipe3(WithErrorPolicy(FailFast(), double), custom, Named("double", double))
+
+		got := Describe(p)
+
+		assert.Equal(t, Topology{
+			Nodes: []TopologyNode{
+				{ID: 0, Kind: "Map", PoolSize: 1},
+				{ID: 1, Name: "double", Kind: "Map", PoolSize: 1},
+				{ID: 2, Kind: "input"},
+				{ID: 3, Kind: "unknown"},
+				{ID: 4, Kind: "output"},
+			},
+			Edges: []TopologyEdge{
+				{From: 2, To: 0},
+				{From: 3, To: 1},
+				{From: 1, To: 4},
+			},
+		}, got)
+
+		assert.Equal(t, `flowchart LR
+	n0["Map<br/>pool: 1, buffer: 0"]
+	n1["double (Map)<br/>pool: 1, buffer: 0"]
+	n2["input"]
+	n3["unknown"]
+	n4["output"]
+	n2 --> n0
+	n3 --> n1
+	n1 --> n4
+`, got.Mermaid())
+	})
+
+	t.Run("aggregations and time operators", func(t *testing.T) {
+		sum := Reduce(func(ctx context.Context, acc, n int) (int, error) {
+			return acc + n, nil
+		})
+
+		p := Pipe5(Of(1, 2, 3), RateLimit[int](10, 1), Debounce[int](time.Second), sum, discard)
+
+		got := Describe(p)
+
+		assert.Equal(t, Topology{
+			Nodes: []TopologyNode{
+				{ID: 0, Kind: "Of"},
+				{ID: 1, Kind: "RateLimit"},
+				{ID: 2, Kind: "Debounce"},
+				{ID: 3, Kind: "Reduce", BufferSize: 1},
+				{ID: 4, Kind: "Do", PoolSize: 2},
+			},
+			Edges: []TopologyEdge{
+				{From: 0, To: 1},
+				{From: 1, To: 2},
+				{From: 2, To: 3},
+				{From: 3, To: 4},
+			},
+		}, got)
+	})
+
+	t.Run("partition by", func(t *testing.T) {
+		p := PartitionBy(func(n int) int { return n }, 4, Pipe(double, Named("double", double)))
+
+		got := Describe(p)
+
+		assert.Equal(t, Topology{
+			Nodes: []TopologyNode{
+				{ID: 0, Kind: "PartitionBy", PoolSize: 4},
+				{ID: 1, Kind: "Map", PoolSize: 1},
+				{ID: 2, Name: "double", Kind: "Map", PoolSize: 1},
+				{ID: 3, Kind: "input"},
+				{ID: 4, Kind: "output"},
+			},
+			Edges: []TopologyEdge{
+				{From: 3, To: 0},
+				{From: 0, To: 1},
+				{From: 1, To: 2},
+				{From: 2, To: 4},
+			},
+		}, got)
+	})
+
+	t.Run("custom stages", func(t *testing.T) {
+		processed := false
+
+		custom := func(ctx context.Context, in Stream[int], errs chan<- error) Stream[int] {
+			out := make(chan int)
+
+			if DescribeStage(ctx, TopologyNode{Kind: "custom"}, in, out) {
+				return out
+			}
+
+			go func() {
+				defer close(out)
+				for v := range in {
+					processed = true
+					out <- v
+				}
+			}()
+
+			return out
+		}
+
+		got := Describe(Pipe(Of(1, 2, 3), Named("custom", custom)))
+
+		assert.Equal(t, Topology{
+			Nodes: []TopologyNode{
+				{ID: 0, Kind: "Of"},
+				{ID: 1, Name: "custom", Kind: "custom"},
+				{ID: 2, Kind: "output"},
+			},
+			Edges: []TopologyEdge{
+				{From: 0, To: 1},
+				{From: 1, To: 2},
+			},
+		}, got)
+
+		assert.False(t, processed)
+	})
+
+	t.Run("describe then run stages with side streams", func(t *testing.T) {
+		ctx := context.Background()
+
+		errDown := errors.New("down")
+
+		cb := NewCircuitBreaker(CircuitBreakerWindow(1, 1))
+		rejected, fallback := CircuitFallbackRoute[int, int]()
+
+		breaker := CircuitBreak(cb, func(ctx context.Context, n int) (int, error) {
+			return 0, errDown
+		}, fallback)
+
+		var letters []DeadLetter[int]
+		collectLetters := Do(func(ctx context.Context, l DeadLetter[int]) error {
+			letters = append(letters, l)
+			return nil
+		})
+
+		p := Pipe(Of(1, 2), WithDeadLetters(collectLetters, breaker))
+
+		Describe(p)
+
+		var got []int
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			got = Collect(rejected)
+		}()
+
+		_, err := RunCollect(ctx, p)
+		assert.NoError(t, err)
+
+		<-done
+		assert.Equal(t, []int{2}, got)
+		assert.Len(t, letters, 1)
+	})
+
+	t.Run("describe without calling the hooks", func(t *testing.T) {
+		var calls atomic.Int32
+
+		gen := FromFunc(func(ctx context.Context) (int, bool, error) {
+			return 0, false, nil
+		}, FromFuncOnBeforeClose(func(ctx context.Context) {
+			calls.Add(1)
+		}))
+
+		sink := Do(func(ctx context.Context, n int) error {
+			return nil
+		}, DoOnBeforeClose(func(ctx context.Context) {
+			calls.Add(1)
+		}))
+
+		Describe(Pipe(gen, sink))
+
+		assert.Equal(t, int32(0), calls.Load())
+
+		assert.NoError(t, Run(context.Background(), Pipe(gen, sink)))
+		assert.Equal(t, int32(2), calls.Load())
+	})
+
+	t.Run("pipelines run normally afterwards", func(t *testing.T) {
+		ctx := context.Background()
+
+		p := Pipe(Of(1, 2, 3), double)
+
+		Describe(p)
+
+		got, err := RunCollect(ctx, p)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []int{2, 4, 6}, got)
+	})
+}
@@ -3,6 +3,7 @@ package rivo
 import (
 	"context"
 	"fmt"
+	"time"
 )
 
 // Do returns a sync pipeline that applies the given function to each item in the stream.
@@ -11,9 +12,19 @@ import (
 func Do[T any](f func(context.Context, T) error, opt ...DoOption) Sync[T] {
 	o := assertDoOptions(opt)
 
+	if o.retry != nil {
+		f = retryDoFunc(o.retry, f)
+	}
+
 	return ForEachOutput[T, None](
 		func(ctx context.Context, val T, out chan<- None, errs chan<- error) {
-			if err := f(ctx, val); err != nil {
+			callCtx, discard := callContext(ctx)
+			err := f(callCtx, val)
+			if discard() {
+				return
+			}
+
+			if err != nil {
 				select {
 				case <-ctx.Done():
 					return
@@ -21,14 +32,40 @@ func Do[T any](f func(context.Context, T) error, opt ...DoOption) Sync[T] {
 				}
 			}
 		},
-		ForEachOutputPoolSize(o.poolSize),
-		ForEachOutputOnBeforeClose(o.onBeforeClose),
+		o.forEachOutputOptions()...,
 	)
 }
 
 type doOptions struct {
 	poolSize      int
 	onBeforeClose func(context.Context)
+	limiter       *tokenBucket
+	retry         *retryOptions
+	itemTimeout   time.Duration
+	recoverPanics bool
+}
+
+func (o *doOptions) forEachOutputOptions() []ForEachOutputOption {
+	opts := []ForEachOutputOption{
+		ForEachOutputPoolSize(o.poolSize),
+		ForEachOutputOnBeforeClose(o.onBeforeClose),
+		forEachOutputKind("Do"),
+		forEachOutputTimeoutCalls(),
+	}
+
+	if o.limiter != nil {
+		opts = append(opts, forEachOutputLimiter(o.limiter))
+	}
+
+	if o.itemTimeout > 0 {
+		opts = append(opts, ForEachOutputItemTimeout(o.itemTimeout))
+	}
+
+	if o.recoverPanics {
+		opts = append(opts, ForEachOutputRecover())
+	}
+
+	return opts
 }
 
 type DoOption func(*doOptions) error
@@ -57,10 +94,65 @@ func DoOnBeforeClose(fn func(context.Context)) DoOption {
 	}
 }
 
+// DoRateLimit limits the rate at which the function is applied to rate items per second, allowing bursts of up to burst items.
+// See ForEachOutputRateLimit.
+func DoRateLimit(rate float64, burst int) DoOption {
+	return func(o *doOptions) error {
+		if err := validateRateLimit(rate, burst); err != nil {
+			return err
+		}
+
+		o.limiter = newTokenBucket(rate, burst)
+
+		return nil
+	}
+}
+
+// DoRetry configures Do to retry the function when it fails, before sending the error to the error channel.
+// See Retry for the available options.
+func DoRetry(opt ...RetryOption) DoOption {
+	return func(o *doOptions) error {
+		retry, err := applyRetryOptions(opt)
+		if err != nil {
+			return err
+		}
+
+		o.retry = retry
+
+		return nil
+	}
+}
+
+// DoItemTimeout sets how long the function can take to process each item. See ForEachOutputItemTimeout.
+func DoItemTimeout(d time.Duration) DoOption {
+	return func(o *doOptions) error {
+		if d <= 0 {
+			return fmt.Errorf("item timeout must be greater than 0")
+		}
+
+		o.itemTimeout = d
+
+		return nil
+	}
+}
+
+// DoRecover configures Do to recover from the panics of the function. See ForEachOutputRecover.
+func DoRecover() DoOption {
+	return func(o *doOptions) error {
+		o.recoverPanics = true
+
+		return nil
+	}
+}
+
 func newDefaultDoOptions() *doOptions {
 	return &doOptions{
 		poolSize:      1,
 		onBeforeClose: func(ctx context.Context) {},
+		limiter:       nil,
+		retry:         nil,
+		itemTimeout:   0,
+		recoverPanics: false,
 	}
 }
 
@@ -0,0 +1,27 @@
+package rivo
+
+import "context"
+
+// DoItems returns a sync pipeline that applies the given function to the value of each successful item in the stream.
+// The errors of the failed items are sent to the error channel, as well as the errors returned by the function,
+// which are wrapped in an ItemError carrying the original value.
+// DoItems accepts the same options as Do. DoRetry retries the function, but not the failed items, while its timeouts
+// and recovered panics are wrapped in an ItemError like its errors.
+func DoItems[T any](f func(context.Context, T) error, opt ...DoOption) Sync[Item[T]] {
+	o := assertDoOptions(opt)
+	call := itemCall{retry: o.retry, timeout: o.itemTimeout, recoverPanics: o.recoverPanics}
+
+	return Do(func(ctx context.Context, item Item[T]) error {
+		if item.Err != nil {
+			return item.Err
+		}
+
+		return call.do(ctx, item.Val, func(ctx context.Context) error {
+			return f(ctx, item.Val)
+		})
+	}, append(opt, func(o *doOptions) error {
+		// The options are applied by the item call
+		o.retry, o.itemTimeout, o.recoverPanics = nil, 0, false
+		return nil
+	})...)
+}
@@ -0,0 +1,42 @@
+package rivo_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	. "github.com/agiac/rivo"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDoItems(t *testing.T) {
+	t.Run("apply function to successful items", func(t *testing.T) {
+		ctx := context.Background()
+
+		upstreamErr := errors.New("upstream error")
+		fnErr := errors.New("fn error")
+
+		g := Of(Item[int]{Val: 1}, Item[int]{Err: upstreamErr}, Item[int]{Val: 3})
+
+		var got []int
+		d := DoItems(func(ctx context.Context, n int) error {
+			if n == 3 {
+				return fnErr
+			}
+			got = append(got, n)
+			return nil
+		})
+
+		err := Run(ctx, Pipe(g, d))
+
+		assert.Equal(t, []int{1}, got)
+		assert.ErrorIs(t, err, upstreamErr)
+
+		var itemErr *ItemError
+		if assert.ErrorAs(t, err, &itemErr) {
+			assert.Equal(t, 3, itemErr.Item)
+			assert.ErrorIs(t, itemErr, fnErr)
+		}
+	})
+}
@@ -7,6 +7,7 @@ import (
 	"sync"
 	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -135,14 +136,40 @@ func TestDo(t *testing.T) {
 				mu.Unlock()
 			}
 		})
-		defer stop()
 
 		<-p(ctx, nil, errs)
 
+		// Wait for the error handler to receive every error before checking them
+		stop()
+
 		assert.Equal(t, 4, count)
 		mu.Lock()
-		assert.Len(t, foundErrs, 1)
-		assert.EqualError(t, foundErrs[0], "error on 3")
-		mu.Unlock()
+		defer mu.Unlock()
+		if assert.Len(t, foundErrs, 1) {
+			assert.EqualError(t, foundErrs[0], "error on 3")
+		}
+	})
+
+	t.Run("with item timeout", func(t *testing.T) {
+		ctx := context.Background()
+
+		var count atomic.Int32
+		d := Do(func(ctx context.Context, n int) error {
+			if n == 2 {
+				<-ctx.Done()
+				return ctx.Err()
+			}
+			count.Add(1)
+			return nil
+		}, DoItemTimeout(10*time.Millisecond))
+
+		err := Run(ctx, Pipe(Of(1, 2, 3), d))
+
+		assert.Equal(t, int32(2), count.Load())
+
+		var timeoutErr *TimeoutError
+		if assert.ErrorAs(t, err, &timeoutErr) {
+			assert.Equal(t, 2, timeoutErr.Item)
+		}
 	})
 }
@@ -0,0 +1,147 @@
+package rivo
+
+import (
+	"context"
+	"sync"
+)
+
+// ErrorPolicy decides when a pipeline must be stopped because of the errors sent by its stages.
+// Use ContinueOnError, FailFast, FailAfter or FailOnErrorRate to create one and WithErrorPolicy to attach it to a pipeline.
+type ErrorPolicy struct {
+	maxErrors     int
+	checkRate     bool
+	maxErrorRate  float64
+	minRateSample int
+}
+
+// ContinueOnError returns an ErrorPolicy that never stops the pipeline. It's the default behaviour of every pipeline.
+func ContinueOnError() ErrorPolicy {
+	return ErrorPolicy{}
+}
+
+// FailFast returns an ErrorPolicy that stops the pipeline on the first error.
+func FailFast() ErrorPolicy {
+	return FailAfter(1)
+}
+
+// FailAfter returns an ErrorPolicy that stops the pipeline once n errors have been sent.
+// It panics if n is less than 1.
+func FailAfter(n int) ErrorPolicy {
+	if n < 1 {
+		panic("n must be greater than 0")
+	}
+
+	return ErrorPolicy{maxErrors: n}
+}
+
+// FailOnErrorRate returns an ErrorPolicy that stops the pipeline when the error rate of one of its stages exceeds
+// rate. The error rate of a stage is the ratio between the items it failed to process and the items it has taken in.
+// It's tracked for the stages built on ForEachOutput, like Map or Do, and for FromFunc, whose calls are its items,
+// while the errors of the other stages are not taken into account.
+// The rate of a stage is checked only once it has taken in at least minSamples items.
+// It panics if rate is not between 0 and 1 or if minSamples is less than 1.
+func FailOnErrorRate(rate float64, minSamples int) ErrorPolicy {
+	if rate < 0 || rate > 1 {
+		panic("rate must be between 0 and 1")
+	}
+
+	if minSamples < 1 {
+		panic("minSamples must be greater than 0")
+	}
+
+	return ErrorPolicy{checkRate: true, maxErrorRate: rate, minRateSample: minSamples}
+}
+
+func (p ErrorPolicy) tooManyErrors(errs int) bool {
+	return p.maxErrors > 0 && errs >= p.maxErrors
+}
+
+func (p ErrorPolicy) errorRateExceeded(items, failed int) bool {
+	return p.checkRate && items >= p.minRateSample && float64(failed)/float64(items) > p.maxErrorRate
+}
+
+// WithErrorPolicy returns a pipeline that runs p and stops it as soon as the given policy says so.
+// The errors sent by p are forwarded to the error channel, if not nil.
+// The pipeline is stopped by cancelling the context of its first stage, so that the following stages,
+// which receive a non-cancellable context from Pipe, can still process and flush the items they already have.
+// Once the pipeline is stopped, the remaining items of the input stream are discarded.
+func WithErrorPolicy[T, U any](policy ErrorPolicy, p Pipeline[T, U]) Pipeline[T, U] {
+	return func(ctx context.Context, in Stream[T], errs chan<- error) Stream[U] {
+		pCtx, cancel := context.WithCancel(ctx)
+
+		if in != nil {
+			// Discard the input once the pipeline has been stopped, so that upstream stages are not blocked.
+			go func() {
+				<-pCtx.Done()
+				for range in {
+				}
+			}()
+		}
+
+		if policy.checkRate {
+			pCtx = context.WithValue(pCtx, errorRateKey{}, &errorRatePolicy{policy: policy, stop: cancel})
+		}
+
+		errorCount := 0
+
+		return interceptErrors(ctx, func(pErrs chan<- error) Stream[U] {
+			return p(pCtx, in, pErrs)
+		}, func(err error) {
+			// The errors are handled by a single goroutine
+			errorCount++
+			if policy.tooManyErrors(errorCount) {
+				cancel()
+			}
+
+			if errs == nil {
+				return
+			}
+
+			select {
+			case <-ctx.Done():
+			case errs <- err:
+			}
+		}, cancel)
+	}
+}
+
+type errorRateKey struct{}
+
+// errorRatePolicy is the FailOnErrorRate policy of a pipeline, with the function stopping it.
+type errorRatePolicy struct {
+	policy ErrorPolicy
+	stop   func()
+}
+
+// errorRate counts the items taken in by a stage of a pipeline with a FailOnErrorRate policy and those it failed
+// to process, stopping the pipeline once the rate is exceeded.
+type errorRate struct {
+	p      *errorRatePolicy
+	mu     sync.Mutex
+	items  int
+	failed int
+}
+
+// stageErrorRate returns the errorRate of a stage, or nil if the pipeline has no FailOnErrorRate policy.
+func stageErrorRate(ctx context.Context) *errorRate {
+	p, ok := ctx.Value(errorRateKey{}).(*errorRatePolicy)
+	if !ok {
+		return nil
+	}
+	return &errorRate{p: p}
+}
+
+// record counts an item taken in by the stage and whether it failed.
+func (r *errorRate) record(failed bool) {
+	r.mu.Lock()
+	r.items++
+	if failed {
+		r.failed++
+	}
+	stop := r.p.policy.errorRateExceeded(r.items, r.failed)
+	r.mu.Unlock()
+
+	if stop {
+		r.p.stop()
+	}
+}
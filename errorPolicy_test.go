@@ -0,0 +1,279 @@
+package rivo_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	. "github.com/agiac/rivo"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func ExampleWithErrorPolicy() {
+	ctx := context.Background()
+
+	// g emits numbers until the third one, which is a bad record, then waits to be stopped
+	var n atomic.Int32
+	g := FromFunc(func(ctx context.Context) (int, bool, error) {
+		switch v := n.Add(1); {
+		case v < 3:
+			return int(v), true, nil
+		case v == 3:
+			return 0, true, errors.New("bad record")
+		default:
+			<-ctx.Done()
+			return 0, false, nil
+		}
+	})
+
+	printInt := Do(func(ctx context.Context, n int) error {
+		fmt.Println(n)
+		return nil
+	})
+
+	p := Pipe(WithErrorPolicy(FailFast(), g), printInt)
+
+	err := Run(ctx, p)
+
+	fmt.Println("ERROR:", err)
+
+	// Output:
+	// 1
+	// 2
+	// ERROR: FromFunc: bad record
+}
+
+func TestWithErrorPolicy(t *testing.T) {
+	// failing returns a generator that emits increasing numbers and fails on the given ones.
+	// After the last failure, it blocks until its context is cancelled.
+	failing := func(failOn ...int32) Generator[int] {
+		var n atomic.Int32
+		return FromFunc(func(ctx context.Context) (int, bool, error) {
+			v := n.Add(1)
+			if v > failOn[len(failOn)-1] {
+				<-ctx.Done()
+				return 0, false, nil
+			}
+			if slices.Contains(failOn, v) {
+				return 0, true, fmt.Errorf("error on %d", v)
+			}
+			return int(v), true, nil
+		})
+	}
+
+	t.Run("continue on error", func(t *testing.T) {
+		ctx := context.Background()
+
+		g := Pipe(Of(1, 2, 3, 4), Map(func(ctx context.Context, n int) (int, error) {
+			if n%2 == 0 {
+				return 0, fmt.Errorf("error on %d", n)
+			}
+			return n, nil
+		}))
+
+		got, err := RunCollect(ctx, WithErrorPolicy(ContinueOnError(), g))
+
+		assert.Equal(t, []int{1, 3}, got)
+		assert.EqualError(t, err, "error on 2\nerror on 4")
+	})
+
+	t.Run("fail fast", func(t *testing.T) {
+		ctx := context.Background()
+
+		got, err := RunCollect(ctx, WithErrorPolicy(FailFast(), failing(5)))
+
+		assert.Equal(t, []int{1, 2, 3, 4}, got)
+		assert.EqualError(t, err, "FromFunc: error on 5")
+	})
+
+	t.Run("fail after n errors", func(t *testing.T) {
+		ctx := context.Background()
+
+		got, err := RunCollect(ctx, WithErrorPolicy(FailAfter(2), failing(3, 6)))
+
+		assert.Equal(t, []int{1, 2, 4, 5}, got)
+		assert.EqualError(t, err, "FromFunc: error on 3\nFromFunc: error on 6")
+	})
+
+	t.Run("fail on error rate", func(t *testing.T) {
+		ctx := context.Background()
+
+		g := failing(11, 12, 13, 14)
+
+		got, err := RunCollect(ctx, WithErrorPolicy(FailOnErrorRate(0.25, 10), g))
+
+		// 10 items and 4 errors make a rate greater than 0.25
+		assert.Len(t, got, 10)
+		assert.EqualError(t, err, "FromFunc: error on 11\nFromFunc: error on 12\nFromFunc: error on 13\nFromFunc: error on 14")
+	})
+
+	t.Run("downstream stages are flushed", func(t *testing.T) {
+		ctx := context.Background()
+
+		var flushed atomic.Bool
+		var count atomic.Int32
+		d := Do(func(ctx context.Context, n int) error {
+			count.Add(1)
+			return nil
+		}, DoOnBeforeClose(func(ctx context.Context) {
+			flushed.Store(true)
+		}))
+
+		err := Run(ctx, Pipe(WithErrorPolicy(FailFast(), failing(10)), d))
+
+		assert.EqualError(t, err, "FromFunc: error on 10")
+		assert.Equal(t, int32(9), count.Load())
+		assert.True(t, flushed.Load())
+	})
+
+	t.Run("upstream is not blocked", func(t *testing.T) {
+		ctx := context.Background()
+
+		m := Map(func(ctx context.Context, n int) (int, error) {
+			if n == 2 {
+				return 0, errors.New("error on 2")
+			}
+			return n, nil
+		})
+
+		in := make(chan int)
+		go func() {
+			defer close(in)
+			for i := 1; i <= 100; i++ {
+				in <- i
+			}
+		}()
+
+		got := Collect(WithErrorPolicy(FailFast(), m)(ctx, in, nil))
+
+		assert.Contains(t, got, 1)
+		assert.NotContains(t, got, 2)
+		assert.Less(t, len(got), 100)
+	})
+
+	t.Run("with run option", func(t *testing.T) {
+		ctx := context.Background()
+
+		var count atomic.Int32
+		d := Do(func(ctx context.Context, n int) error {
+			count.Add(1)
+			return nil
+		})
+
+		err := Run(ctx, Pipe(failing(4), d), RunErrorPolicy(FailFast()))
+
+		assert.EqualError(t, err, "FromFunc: error on 4")
+		assert.Equal(t, int32(3), count.Load())
+	})
+
+	t.Run("fail on error rate with run option", func(t *testing.T) {
+		ctx := context.Background()
+
+		var count atomic.Int32
+		d := Do(func(ctx context.Context, n int) error {
+			count.Add(1)
+			if n%10 == 0 {
+				return fmt.Errorf("error on %d", n)
+			}
+			return nil
+		})
+
+		items := make([]int, 100)
+		for i := range items {
+			items[i] = i + 1
+		}
+
+		// The items taken in by Do are counted, even if the pipeline emits none
+		err := Run(ctx, Pipe(Of(items...), d), RunErrorPolicy(FailOnErrorRate(0.5, 10)))
+
+		assert.Error(t, err)
+		assert.Equal(t, int32(100), count.Load())
+	})
+
+	t.Run("fail on error rate with run option stops the pipeline", func(t *testing.T) {
+		ctx := context.Background()
+
+		var count atomic.Int32
+		d := Do(func(ctx context.Context, n int) error {
+			count.Add(1)
+			if n%2 == 0 {
+				return fmt.Errorf("error on %d", n)
+			}
+			return nil
+		})
+
+		var n atomic.Int32
+		in := FromFunc(func(ctx context.Context) (int, bool, error) {
+			return int(n.Add(1)), true, nil
+		})
+
+		// Half of the items fail, which exceeds the rate of 0.25 once 10 items have been processed
+		err := Run(ctx, Pipe(in, d), RunErrorPolicy(FailOnErrorRate(0.25, 10)))
+
+		assert.Error(t, err)
+		assert.Less(t, count.Load(), int32(100))
+	})
+
+	// failOneIn10 returns a generator of 100 items, mapped by a stage that fails on one item in 10, starting from the first.
+	failOneIn10 := func() Generator[int] {
+		items := make([]int, 100)
+		for i := range items {
+			items[i] = i + 1
+		}
+
+		m := Map(func(ctx context.Context, n int) (int, error) {
+			if n%10 == 1 {
+				return 0, fmt.Errorf("error on %d", n)
+			}
+			return n, nil
+		})
+
+		return Pipe(Of(items...), m)
+	}
+
+	t.Run("fail on error rate with a batching stage", func(t *testing.T) {
+		ctx := context.Background()
+
+		g := failOneIn10()
+
+		var count atomic.Int32
+		d := Do(func(ctx context.Context, batch []int) error {
+			count.Add(int32(len(batch)))
+			return nil
+		})
+
+		// The errors of Map are compared to the items taken in by Map, rather than to the batches
+		err := Run(ctx, Pipe3(g, Batch[int](10), d), RunErrorPolicy(FailOnErrorRate(0.5, 10)))
+
+		assert.Equal(t, 10, strings.Count(err.Error(), "error on"))
+		assert.Equal(t, int32(90), count.Load())
+	})
+
+	t.Run("fail on error rate with a sink", func(t *testing.T) {
+		ctx := context.Background()
+
+		g := failOneIn10()
+
+		var count atomic.Int32
+		d := Do(func(ctx context.Context, n int) error {
+			count.Add(1)
+			return nil
+		})
+
+		err := Run(ctx, Pipe(g, Connect(d, d)), RunErrorPolicy(FailOnErrorRate(0.5, 10)))
+
+		assert.Equal(t, 10, strings.Count(err.Error(), "error on"))
+		assert.Equal(t, int32(180), count.Load())
+	})
+}
+
+func TestErrorPolicyPanics(t *testing.T) {
+	assert.Panics(t, func() { FailAfter(0) })
+	assert.Panics(t, func() { FailOnErrorRate(1.5, 1) })
+	assert.Panics(t, func() { FailOnErrorRate(0.5, 0) })
+}
@@ -17,7 +17,7 @@ func main() {
 	// Create a generator with string values
 	g := rivo.Of("1", "2", "invalid", "4", "5")
 
-	// Transform string to Item[int] with error handling
+	// Transform string to int, sending conversion errors to the error channel
 	toInt := rivo.Map(func(ctx context.Context, s string) (int, error) {
 		return strconv.Atoi(s)
 	})
@@ -28,22 +28,16 @@ func main() {
 		return nil
 	})
 
-	// Handle errors
-	handleErrors := func(ctx context.Context, err error) {
-		fmt.Println("ERROR:", err)
-	}
-
-	// Run the error handler in a separate goroutine
-	errs, wait := rivo.RunErrorSyncFunc(ctx, handleErrors)
-	defer wait()
-
 	p := rivo.Pipe3(g, toInt, handleValues)
 
-	<-p(ctx, nil, errs)
+	// Run the pipeline, collecting the errors sent by any of its stages
+	if err := rivo.Run(ctx, p); err != nil {
+		fmt.Println("ERROR:", err)
+	}
 
 	// Value: 1
 	// Value: 2
-	// ERROR: strconv.Atoi: parsing "invalid": invalid syntax
 	// Value: 4
 	// Value: 5
+	// ERROR: strconv.Atoi: parsing "invalid": invalid syntax
 }
@@ -3,6 +3,7 @@ package rivo
 import (
 	"context"
 	"fmt"
+	"time"
 )
 
 // Filter returns a pipeline that filters the input stream using the given function.
@@ -11,7 +12,12 @@ func Filter[T any](f func(context.Context, T) (bool, error), opt ...FilterOption
 
 	return ForEachOutput[T, T](
 		func(ctx context.Context, val T, out chan<- T, errs chan<- error) {
-			ok, err := f(ctx, val)
+			callCtx, discard := callContext(ctx)
+			ok, err := f(callCtx, val)
+			if discard() {
+				return
+			}
+
 			if err != nil {
 				select {
 				case <-ctx.Done():
@@ -29,14 +35,34 @@ func Filter[T any](f func(context.Context, T) (bool, error), opt ...FilterOption
 				}
 			}
 		},
-		ForEachOutputPoolSize(o.poolSize),
-		ForEachOutputBufferSize(o.bufferSize),
+		o.forEachOutputOptions()...,
 	)
 }
 
 type filterOptions struct {
-	poolSize   int
-	bufferSize int
+	poolSize      int
+	bufferSize    int
+	itemTimeout   time.Duration
+	recoverPanics bool
+}
+
+func (o filterOptions) forEachOutputOptions() []ForEachOutputOption {
+	opts := []ForEachOutputOption{
+		ForEachOutputPoolSize(o.poolSize),
+		ForEachOutputBufferSize(o.bufferSize),
+		forEachOutputKind("Filter"),
+		forEachOutputTimeoutCalls(),
+	}
+
+	if o.itemTimeout > 0 {
+		opts = append(opts, ForEachOutputItemTimeout(o.itemTimeout))
+	}
+
+	if o.recoverPanics {
+		opts = append(opts, ForEachOutputRecover())
+	}
+
+	return opts
 }
 
 type FilterOption func(*filterOptions) error
@@ -65,9 +91,33 @@ func FilterBufferSize(n int) FilterOption {
 	}
 }
 
+// FilterItemTimeout sets how long the function can take to process each item. See ForEachOutputItemTimeout.
+func FilterItemTimeout(d time.Duration) FilterOption {
+	return func(o *filterOptions) error {
+		if d <= 0 {
+			return fmt.Errorf("item timeout must be greater than 0")
+		}
+
+		o.itemTimeout = d
+
+		return nil
+	}
+}
+
+// FilterRecover configures Filter to recover from the panics of the function. See ForEachOutputRecover.
+func FilterRecover() FilterOption {
+	return func(o *filterOptions) error {
+		o.recoverPanics = true
+
+		return nil
+	}
+}
+
 var filterDefaultOptions = filterOptions{
-	poolSize:   1,
-	bufferSize: 0,
+	poolSize:      1,
+	bufferSize:    0,
+	itemTimeout:   0,
+	recoverPanics: false,
 }
 
 func applyFilterOptions(opt []FilterOption) (filterOptions, error) {
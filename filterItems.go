@@ -0,0 +1,47 @@
+package rivo
+
+import "context"
+
+// FilterItems returns a pipeline that filters the successful items of the input stream using the given function.
+// Failed items are forwarded unchanged. If the function returns an error, the item is emitted with an ItemError
+// wrapping it and carrying the original value, instead of sending the error to the error channel.
+// FilterItems accepts the same options as Filter. The timeouts and recovered panics of the function are emitted
+// in-band like its errors.
+func FilterItems[T any](f func(context.Context, T) (bool, error), opt ...FilterOption) Pipeline[Item[T], Item[T]] {
+	o := assertFilterOptions(opt)
+	call := itemCall{timeout: o.itemTimeout, recoverPanics: o.recoverPanics}
+
+	// The options are applied by the item call
+	o.itemTimeout, o.recoverPanics = 0, false
+
+	return ForEachOutput[Item[T], Item[T]](
+		func(ctx context.Context, item Item[T], out chan<- Item[T], errs chan<- error) {
+			if item.Err == nil {
+				var ok bool
+				err := call.do(ctx, item.Val, func(ctx context.Context) error {
+					var err error
+					ok, err = f(ctx, item.Val)
+					return err
+				})
+
+				// The pipeline has been stopped
+				if ctx.Err() != nil {
+					return
+				}
+
+				if err != nil {
+					item = Item[T]{Err: err}
+				} else if !ok {
+					return
+				}
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case out <- item:
+			}
+		},
+		append(o.forEachOutputOptions(), forEachOutputKind("FilterItems"))...,
+	)
+}
@@ -0,0 +1,52 @@
+package rivo_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	. "github.com/agiac/rivo"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFilterItems(t *testing.T) {
+	t.Run("filter successful items", func(t *testing.T) {
+		ctx := context.Background()
+
+		upstreamErr := errors.New("upstream error")
+		fnErr := errors.New("fn error")
+
+		g := Of(Item[int]{Val: 1}, Item[int]{Val: 2}, Item[int]{Err: upstreamErr}, Item[int]{Val: 3}, Item[int]{Val: 4})
+
+		onlyEven := FilterItems(func(ctx context.Context, n int) (bool, error) {
+			if n == 3 {
+				return false, fnErr
+			}
+			return n%2 == 0, nil
+		})
+
+		got := Collect(Pipe(g, onlyEven)(ctx, nil, nil))
+
+		assert.Len(t, got, 4)
+		assert.Equal(t, Item[int]{Val: 2}, got[0])
+		assert.Equal(t, Item[int]{Err: upstreamErr}, got[1])
+		assert.Equal(t, Item[int]{Err: &ItemError{Item: 3, Err: fnErr}}, got[2])
+		assert.Equal(t, Item[int]{Val: 4}, got[3])
+	})
+
+	t.Run("with buffer size", func(t *testing.T) {
+		ctx := context.Background()
+
+		all := FilterItems(func(ctx context.Context, n int) (bool, error) {
+			return true, nil
+		}, FilterBufferSize(3))
+
+		out := Pipe(Of(Item[int]{Val: 1}), all)(ctx, nil, nil)
+
+		got := Collect(out)
+
+		assert.Equal(t, 3, cap(out))
+		assert.Equal(t, []Item[int]{{Val: 1}}, got)
+	})
+}
@@ -3,15 +3,25 @@ package rivo
 import (
 	"context"
 	"fmt"
+	"time"
 )
 
 // FilterMap returns a pipeline that filters and maps items from the input stream.
 func FilterMap[T, U any](f func(context.Context, T) (bool, U, error), opt ...FilterMapOption) Pipeline[T, U] {
 	o := assertFilterMapOptions(opt)
 
+	if o.retry != nil {
+		f = retryFilterMapFunc(o.retry, f)
+	}
+
 	return ForEachOutput[T, U](
 		func(ctx context.Context, val T, out chan<- U, errs chan<- error) {
-			keep, mapped, err := f(ctx, val)
+			callCtx, discard := callContext(ctx)
+			keep, mapped, err := f(callCtx, val)
+			if discard() {
+				return
+			}
+
 			if err != nil {
 				select {
 				case <-ctx.Done():
@@ -31,14 +41,50 @@ func FilterMap[T, U any](f func(context.Context, T) (bool, U, error), opt ...Fil
 			case out <- mapped:
 			}
 		},
-		ForEachOutputPoolSize(o.poolSize),
-		ForEachOutputBufferSize(o.bufferSize),
+		o.forEachOutputOptions()...,
 	)
 }
 
 type filterMapOptions struct {
-	poolSize   int
-	bufferSize int
+	poolSize      int
+	bufferSize    int
+	preserveOrder bool
+	maxAhead      int
+	limiter       *tokenBucket
+	retry         *retryOptions
+	itemTimeout   time.Duration
+	recoverPanics bool
+}
+
+func (o filterMapOptions) forEachOutputOptions() []ForEachOutputOption {
+	opts := []ForEachOutputOption{
+		ForEachOutputPoolSize(o.poolSize),
+		ForEachOutputBufferSize(o.bufferSize),
+		forEachOutputKind("FilterMap"),
+		forEachOutputTimeoutCalls(),
+	}
+
+	if o.preserveOrder {
+		opts = append(opts, ForEachOutputPreserveOrder())
+	}
+
+	if o.maxAhead > 0 {
+		opts = append(opts, ForEachOutputMaxAhead(o.maxAhead))
+	}
+
+	if o.limiter != nil {
+		opts = append(opts, forEachOutputLimiter(o.limiter))
+	}
+
+	if o.itemTimeout > 0 {
+		opts = append(opts, ForEachOutputItemTimeout(o.itemTimeout))
+	}
+
+	if o.recoverPanics {
+		opts = append(opts, ForEachOutputRecover())
+	}
+
+	return opts
 }
 
 type FilterMapOption func(*filterMapOptions) error
@@ -67,9 +113,86 @@ func FilterMapBufferSize(n int) FilterMapOption {
 	}
 }
 
+// FilterMapPreserveOrder configures FilterMap to emit the kept items in the same order as the input items,
+// even with a pool size greater than 1.
+func FilterMapPreserveOrder() FilterMapOption {
+	return func(o *filterMapOptions) error {
+		o.preserveOrder = true
+
+		return nil
+	}
+}
+
+// FilterMapMaxAhead sets how many items can be processed ahead of the oldest item not emitted yet, when the order is preserved.
+// See ForEachOutputMaxAhead.
+func FilterMapMaxAhead(n int) FilterMapOption {
+	return func(o *filterMapOptions) error {
+		if n < 1 {
+			return fmt.Errorf("max ahead must be greater than 0")
+		}
+
+		o.maxAhead = n
+
+		return nil
+	}
+}
+
+// FilterMapRateLimit limits the rate at which the items are processed to rate items per second, allowing bursts of up to burst items.
+// See ForEachOutputRateLimit.
+func FilterMapRateLimit(rate float64, burst int) FilterMapOption {
+	return func(o *filterMapOptions) error {
+		if err := validateRateLimit(rate, burst); err != nil {
+			return err
+		}
+
+		o.limiter = newTokenBucket(rate, burst)
+
+		return nil
+	}
+}
+
+// FilterMapRetry configures FilterMap to retry the function when it fails, before sending the error to the error channel.
+// See Retry for the available options.
+func FilterMapRetry(opt ...RetryOption) FilterMapOption {
+	return func(o *filterMapOptions) error {
+		retry, err := applyRetryOptions(opt)
+		if err != nil {
+			return err
+		}
+
+		o.retry = retry
+
+		return nil
+	}
+}
+
+// FilterMapItemTimeout sets how long the function can take to process each item. See ForEachOutputItemTimeout.
+func FilterMapItemTimeout(d time.Duration) FilterMapOption {
+	return func(o *filterMapOptions) error {
+		if d <= 0 {
+			return fmt.Errorf("item timeout must be greater than 0")
+		}
+
+		o.itemTimeout = d
+
+		return nil
+	}
+}
+
+// FilterMapRecover configures FilterMap to recover from the panics of the function. See ForEachOutputRecover.
+func FilterMapRecover() FilterMapOption {
+	return func(o *filterMapOptions) error {
+		o.recoverPanics = true
+
+		return nil
+	}
+}
+
 var filterMapDefaultOptions = filterMapOptions{
-	poolSize:   1,
-	bufferSize: 0,
+	poolSize:      1,
+	bufferSize:    0,
+	preserveOrder: false,
+	maxAhead:      0,
 }
 
 func applyFilterMapOptions(opt []FilterMapOption) (filterMapOptions, error) {
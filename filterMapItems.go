@@ -0,0 +1,36 @@
+package rivo
+
+import "context"
+
+// FilterMapItems returns a pipeline that filters and maps the value of each successful item from the input stream.
+// Failed items are forwarded unchanged. If the function returns an error, the item is emitted with an ItemError
+// wrapping it and carrying the original value, instead of sending the error to the error channel.
+// FilterMapItems accepts the same options as FilterMap. FilterMapRetry retries the function, while its timeouts and
+// recovered panics are emitted in-band like its errors.
+func FilterMapItems[T, U any](f func(context.Context, T) (bool, U, error), opt ...FilterMapOption) Pipeline[Item[T], Item[U]] {
+	o := assertFilterMapOptions(opt)
+	call := itemCall{retry: o.retry, timeout: o.itemTimeout, recoverPanics: o.recoverPanics}
+
+	return FilterMap(func(ctx context.Context, item Item[T]) (bool, Item[U], error) {
+		if item.Err != nil {
+			return true, Item[U]{Err: item.Err}, nil
+		}
+
+		var keep bool
+		var v U
+		err := call.do(ctx, item.Val, func(ctx context.Context) error {
+			var err error
+			keep, v, err = f(ctx, item.Val)
+			return err
+		})
+		if err != nil {
+			return true, Item[U]{Err: err}, nil
+		}
+
+		return keep, Item[U]{Val: v}, nil
+	}, append(opt, func(o *filterMapOptions) error {
+		// The options are applied by the item call
+		o.retry, o.itemTimeout, o.recoverPanics = nil, 0, false
+		return nil
+	})...)
+}
@@ -0,0 +1,41 @@
+package rivo_test
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+
+	. "github.com/agiac/rivo"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFilterMapItems(t *testing.T) {
+	t.Run("filter and map successful items", func(t *testing.T) {
+		ctx := context.Background()
+
+		upstreamErr := errors.New("upstream error")
+		fnErr := errors.New("fn error")
+
+		g := Of(Item[int]{Val: 1}, Item[int]{Val: 2}, Item[int]{Err: upstreamErr}, Item[int]{Val: 3}, Item[int]{Val: 4})
+
+		evenToString := FilterMapItems(func(ctx context.Context, n int) (bool, string, error) {
+			if n == 3 {
+				return false, "", fnErr
+			}
+			return n%2 == 0, strconv.Itoa(n), nil
+		})
+
+		got := Collect(Pipe(g, evenToString)(ctx, nil, nil))
+
+		want := []Item[string]{
+			{Val: "2"},
+			{Err: upstreamErr},
+			{Err: &ItemError{Item: 3, Err: fnErr}},
+			{Val: "4"},
+		}
+
+		assert.Equal(t, want, got)
+	})
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"testing"
+	"time"
 
 	. "github.com/agiac/rivo"
 
@@ -91,4 +92,38 @@ func TestFilterMap(t *testing.T) {
 
 		assert.ElementsMatch(t, want, got) // Order might not be guaranteed with pool size > 1
 	})
+
+	t.Run("with preserve order", func(t *testing.T) {
+		ctx := context.Background()
+
+		slowFilterMapFunc := func(ctx context.Context, n int) (bool, string, error) {
+			time.Sleep(time.Duration(10-n) * time.Millisecond)
+			return filterMapFunc(ctx, n)
+		}
+
+		g := Of(1, 2, 3, 4, 5, 6, 7, 8, 9)
+		fm := FilterMap(slowFilterMapFunc, FilterMapPoolSize(4), FilterMapPreserveOrder(), FilterMapMaxAhead(4))
+
+		got := Collect(Pipe(g, fm)(ctx, nil, nil))
+		want := []string{"even-2", "even-4", "even-6", "even-8"}
+
+		assert.Equal(t, want, got)
+	})
+
+	t.Run("with item timeout", func(t *testing.T) {
+		ctx := context.Background()
+
+		fm := FilterMap(func(ctx context.Context, n int) (bool, int, error) {
+			if n == 2 {
+				<-ctx.Done()
+				return false, 0, ctx.Err()
+			}
+			return true, n, nil
+		}, FilterMapItemTimeout(10*time.Millisecond))
+
+		got, err := RunCollect(ctx, Pipe(Of(1, 2, 3), fm))
+
+		assert.Equal(t, []int{1, 3}, got)
+		assert.EqualError(t, err, "item timed out after 10ms")
+	})
 }
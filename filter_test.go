@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"testing"
+	"time"
 
 	. "github.com/agiac/rivo"
 
@@ -84,4 +85,24 @@ func TestFilter(t *testing.T) {
 
 		assert.ElementsMatch(t, want, got)
 	})
+
+	t.Run("with item timeout", func(t *testing.T) {
+		ctx := context.Background()
+
+		f := Filter(func(ctx context.Context, n int) (bool, error) {
+			if n == 2 {
+				<-ctx.Done()
+			}
+			return true, nil
+		}, FilterItemTimeout(10*time.Millisecond))
+
+		got, err := RunCollect(ctx, Pipe(Of(1, 2, 3), f))
+
+		assert.Equal(t, []int{1, 3}, got)
+
+		var timeoutErr *TimeoutError
+		if assert.ErrorAs(t, err, &timeoutErr) {
+			assert.Equal(t, 2, timeoutErr.Item)
+		}
+	})
 }
@@ -14,5 +14,5 @@ func Flatten[T any]() Pipeline[[]T, T] {
 			case out <- item:
 			}
 		}
-	})
+	}, forEachOutputKind("Flatten"))
 }
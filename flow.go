@@ -0,0 +1,103 @@
+package rivo
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+)
+
+// FlowBuilder composes any number of pipelines of any type, checking that the output type of each stage matches
+// the input type of the next one when the flow is built. See Flow.
+// A FlowBuilder is immutable: Then returns a new builder, so that a flow can be extended in different ways.
+type FlowBuilder[A, B any] struct {
+	stages []any
+}
+
+// Flow returns an empty FlowBuilder for a pipeline with input type A and output type B. It's an alternative to
+// Pipe2..Pipe10 for flows with many stages, or whose stages are only known at run time:
+//
+//	p, err := Flow[string, Order]().Then(parse).Then(validate).Then(enrich).Build()
+func Flow[A, B any]() FlowBuilder[A, B] {
+	return FlowBuilder[A, B]{}
+}
+
+// Then returns a builder that appends the given stages to the flow. Each stage must be a Pipeline.
+func (f FlowBuilder[A, B]) Then(stage ...any) FlowBuilder[A, B] {
+	stages := make([]any, 0, len(f.stages)+len(stage))
+	stages = append(stages, f.stages...)
+	stages = append(stages, stage...)
+
+	return FlowBuilder[A, B]{stages: stages}
+}
+
+var (
+	contextType = reflect.TypeFor[context.Context]()
+	errsType    = reflect.TypeFor[chan<- error]()
+)
+
+// Build validates the stages of the flow and returns a pipeline that runs them in order, as Pipe does.
+// It returns an error if a stage is not a Pipeline, if the types of two adjacent stages don't match or if the
+// input and output types of the flow don't match those of its first and last stages.
+func (f FlowBuilder[A, B]) Build() (Pipeline[A, B], error) {
+	stages := make([]reflect.Value, len(f.stages))
+
+	prev := reflect.TypeFor[Stream[A]]()
+
+	for i, stage := range f.stages {
+		v := reflect.ValueOf(stage)
+
+		if !v.IsValid() || v.Kind() == reflect.Func && v.IsNil() {
+			return nil, fmt.Errorf("stage %d: pipeline is nil", i)
+		}
+
+		if !isPipeline(v.Type()) {
+			return nil, fmt.Errorf("stage %d: %T is not a pipeline", i, stage)
+		}
+
+		if in := v.Type().In(1); in != prev {
+			if i == 0 {
+				return nil, fmt.Errorf("stage 0: input type %v does not match the input type %v of the flow", in.Elem(), prev.Elem())
+			}
+			return nil, fmt.Errorf("stage %d: input type %v does not match the output type %v of stage %d", i, in.Elem(), prev.Elem(), i-1)
+		}
+
+		stages[i] = v
+		prev = v.Type().Out(0)
+	}
+
+	if out := reflect.TypeFor[Stream[B]](); prev != out {
+		if len(stages) == 0 {
+			return nil, fmt.Errorf("empty flow: input type %v does not match the output type %v of the flow", prev.Elem(), out.Elem())
+		}
+		return nil, fmt.Errorf("stage %d: output type %v does not match the output type %v of the flow", len(stages)-1, prev.Elem(), out.Elem())
+	}
+
+	return func(ctx context.Context, in Stream[A], errs chan<- error) Stream[B] {
+		stream := reflect.ValueOf(in)
+		errsV := reflect.ValueOf(errs)
+
+		for i, stage := range stages {
+			stageCtx := ctx
+			if i > 0 {
+				stageCtx = context.WithoutCancel(ctx)
+			}
+
+			stream = stage.Call([]reflect.Value{reflect.ValueOf(stageCtx), stream, errsV})[0]
+		}
+
+		return stream.Interface().(Stream[B])
+	}, nil
+}
+
+// isPipeline reports whether t is a Pipeline type.
+func isPipeline(t reflect.Type) bool {
+	if t.Kind() != reflect.Func || t.NumIn() != 3 || t.NumOut() != 1 || t.IsVariadic() {
+		return false
+	}
+
+	in, out := t.In(1), t.Out(0)
+
+	return t.In(0) == contextType && t.In(2) == errsType &&
+		in.Kind() == reflect.Chan && in.ChanDir() == reflect.RecvDir &&
+		out.Kind() == reflect.Chan && out.ChanDir() == reflect.RecvDir
+}
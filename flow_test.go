@@ -0,0 +1,144 @@
+package rivo_test
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"testing"
+
+	. "github.com/agiac/rivo"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func ExampleFlow() {
+	ctx := context.Background()
+
+	parse := Map(func(ctx context.Context, s string) (int, error) {
+		return strconv.Atoi(s)
+	})
+
+	double := Map(func(ctx context.Context, n int) (int, error) {
+		return n * 2, nil
+	})
+
+	format := Map(func(ctx context.Context, n int) (string, error) {
+		return fmt.Sprintf("<%d>", n), nil
+	})
+
+	p, err := Flow[string, string]().Then(parse, double).Then(format).Build()
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	got, _ := RunCollect(ctx, Pipe(Of("1", "2", "3"), p))
+
+	fmt.Println(got)
+
+	_, err = Flow[string, string]().Then(parse, format, double).Build()
+
+	fmt.Println(err)
+
+	// Output:
+	// [<2> <4> <6>]
+	// stage 2: input type int does not match the output type string of stage 1
+}
+
+func TestFlow(t *testing.T) {
+	parse := Map(func(ctx context.Context, s string) (int, error) {
+		return strconv.Atoi(s)
+	})
+
+	double := Map(func(ctx context.Context, n int) (int, error) {
+		return n * 2, nil
+	})
+
+	format := Map(func(ctx context.Context, n int) (string, error) {
+		return strconv.Itoa(n), nil
+	})
+
+	t.Run("compose stages in order", func(t *testing.T) {
+		ctx := context.Background()
+
+		p, err := Flow[string, string]().Then(parse).Then(double, double).Then(format).Build()
+		assert.NoError(t, err)
+
+		got, err := RunCollect(ctx, Pipe(Of("1", "x", "3"), p))
+
+		assert.Equal(t, []string{"4", "12"}, got)
+		assert.EqualError(t, err, `strconv.Atoi: parsing "x": invalid syntax`)
+	})
+
+	t.Run("generators and sinks", func(t *testing.T) {
+		ctx := context.Background()
+
+		var got []int
+
+		sink := Do(func(ctx context.Context, n int) error {
+			got = append(got, n)
+			return nil
+		})
+
+		p, err := Flow[None, None]().Then(Of("1", "2"), parse, double, sink).Build()
+		assert.NoError(t, err)
+
+		assert.NoError(t, Run(ctx, p))
+		assert.Equal(t, []int{2, 4}, got)
+	})
+
+	t.Run("empty flow", func(t *testing.T) {
+		ctx := context.Background()
+
+		p, err := Flow[int, int]().Build()
+		assert.NoError(t, err)
+
+		got, err := RunCollect(ctx, Pipe(Of(1, 2), p))
+
+		assert.NoError(t, err)
+		assert.Equal(t, []int{1, 2}, got)
+
+		_, err = Flow[int, string]().Build()
+
+		assert.EqualError(t, err, "empty flow: input type int does not match the output type string of the flow")
+	})
+
+	t.Run("mismatched types", func(t *testing.T) {
+		_, err := Flow[string, string]().Then(double).Build()
+		assert.EqualError(t, err, "stage 0: input type int does not match the input type string of the flow")
+
+		_, err = Flow[string, string]().Then(parse, parse).Build()
+		assert.EqualError(t, err, "stage 1: input type string does not match the output type int of stage 0")
+
+		_, err = Flow[string, string]().Then(parse, double).Build()
+		assert.EqualError(t, err, "stage 1: output type int does not match the output type string of the flow")
+	})
+
+	t.Run("invalid stages", func(t *testing.T) {
+		_, err := Flow[string, int]().Then("parse").Build()
+		assert.EqualError(t, err, "stage 0: string is not a pipeline")
+
+		_, err = Flow[string, int]().Then(parse, func(n int) int { return n }).Build()
+		assert.EqualError(t, err, "stage 1: func(int) int is not a pipeline")
+
+		_, err = Flow[string, int]().Then(nil).Build()
+		assert.EqualError(t, err, "stage 0: pipeline is nil")
+
+		var p Pipeline[string, int]
+		_, err = Flow[string, int]().Then(p).Build()
+		assert.EqualError(t, err, "stage 0: pipeline is nil")
+	})
+
+	t.Run("builders are immutable", func(t *testing.T) {
+		base := Flow[string, int]().Then(parse)
+
+		_, err := base.Then(double).Build()
+		assert.NoError(t, err)
+
+		_, err = base.Then(format).Build()
+		assert.Error(t, err)
+
+		_, err = base.Build()
+		assert.NoError(t, err)
+	})
+}
@@ -0,0 +1,95 @@
+package rivo
+
+import (
+	"context"
+	"fmt"
+)
+
+// Fold returns a pipeline that accumulates the items of the input stream, starting from the initial value,
+// and emits the accumulated value once the input stream is closed.
+// If the function returns an error, it's sent to the error channel and the item is skipped.
+// If the context is cancelled, nothing is emitted, unless FoldEmitPartial is used.
+func Fold[T, U any](initial U, f func(ctx context.Context, acc U, val T) (U, error), opt ...FoldOption) Pipeline[T, U] {
+	o := assertFoldOptions(opt)
+
+	return func(ctx context.Context, in Stream[T], errs chan<- error) Stream[U] {
+		// The output is buffered, so that the result can always be emitted, even after the context is cancelled.
+		out := make(chan U, 1)
+
+		if DescribeStage(ctx, TopologyNode{Kind: "Fold", BufferSize: 1}, in, out) {
+			return out
+		}
+
+		go func() {
+			defer close(out)
+
+			acc := initial
+
+			for {
+				select {
+				case <-ctx.Done():
+					if o.emitPartial {
+						out <- acc
+					}
+					return
+				case v, ok := <-in:
+					if !ok {
+						out <- acc
+						return
+					}
+
+					next, err := f(ctx, acc, v)
+					if err != nil {
+						select {
+						case <-ctx.Done():
+						case errs <- err:
+						}
+						continue
+					}
+
+					acc = next
+				}
+			}
+		}()
+
+		return out
+	}
+}
+
+type foldOptions struct {
+	emitPartial bool
+}
+
+type FoldOption func(*foldOptions) error
+
+// FoldEmitPartial configures Fold to emit the value accumulated so far when the context is cancelled.
+func FoldEmitPartial() FoldOption {
+	return func(o *foldOptions) error {
+		o.emitPartial = true
+		return nil
+	}
+}
+
+func newDefaultFoldOptions() *foldOptions {
+	return &foldOptions{
+		emitPartial: false,
+	}
+}
+
+func applyFoldOptions(opt []FoldOption) (*foldOptions, error) {
+	opts := newDefaultFoldOptions()
+	for _, o := range opt {
+		if err := o(opts); err != nil {
+			return opts, err
+		}
+	}
+	return opts, nil
+}
+
+func assertFoldOptions(opt []FoldOption) *foldOptions {
+	opts, err := applyFoldOptions(opt)
+	if err != nil {
+		panic(fmt.Errorf("invalid fold options: %v", err))
+	}
+	return opts
+}
@@ -0,0 +1,94 @@
+package rivo_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"testing"
+
+	. "github.com/agiac/rivo"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func ExampleFold() {
+	ctx := context.Background()
+
+	in := Of(1, 2, 3, 4, 5)
+
+	join := Fold("", func(ctx context.Context, acc string, n int) (string, error) {
+		return acc + strconv.Itoa(n), nil
+	})
+
+	for s := range Pipe(in, join)(ctx, nil, nil) {
+		fmt.Println(s)
+	}
+
+	// Output:
+	// 12345
+}
+
+func TestFold(t *testing.T) {
+	sum := func(ctx context.Context, acc int, n int) (int, error) {
+		return acc + n, nil
+	}
+
+	t.Run("fold all items", func(t *testing.T) {
+		ctx := context.Background()
+
+		got := Collect(Pipe(Of(1, 2, 3), Fold(10, sum))(ctx, nil, nil))
+
+		assert.Equal(t, []int{16}, got)
+	})
+
+	t.Run("empty stream", func(t *testing.T) {
+		ctx := context.Background()
+
+		got := Collect(Pipe(Of[int](), Fold(10, sum))(ctx, nil, nil))
+
+		assert.Equal(t, []int{10}, got)
+	})
+
+	t.Run("skip items with errors", func(t *testing.T) {
+		ctx := context.Background()
+
+		f := Fold(0, func(ctx context.Context, acc int, n int) (int, error) {
+			if n == 2 {
+				return 0, errors.New("error on 2")
+			}
+			return acc + n, nil
+		})
+
+		got, err := RunCollect(ctx, Pipe(Of(1, 2, 3), f))
+
+		assert.Equal(t, []int{4}, got)
+		assert.EqualError(t, err, "error on 2")
+	})
+
+	t.Run("context cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+
+		in := make(chan int)
+		out := Fold(0, sum)(ctx, in, nil)
+
+		in <- 1
+		in <- 2
+		cancel()
+
+		assert.Empty(t, Collect(out))
+	})
+
+	t.Run("context cancelled with emit partial", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+
+		in := make(chan int)
+		out := Fold(0, sum, FoldEmitPartial())(ctx, in, nil)
+
+		in <- 1
+		in <- 2
+		cancel()
+
+		assert.Equal(t, []int{3}, Collect(out))
+	})
+}
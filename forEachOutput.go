@@ -5,11 +5,20 @@ import (
 	"errors"
 	"fmt"
 	"sync"
+	"time"
 )
 
 // ForEachOutput returns a pipeline that applies a function to each item from the input stream.
 // The function can write directly to the output channel. The output channel should not be closed by the function,
 // since the output stream will be closed when the input stream is closed or the context is done.
+// By default, with a pool size greater than 1 the items are emitted in the order in which they are processed;
+// use ForEachOutputPreserveOrder to emit them in the order of the input stream.
+// With ForEachOutputItemTimeout, the function receives a context with a deadline for each item and a TimeoutError
+// is sent to the error channel for the items whose deadline is exceeded.
+// Within a stage named with Named, the errors sent by the function are wrapped in StageErrors carrying the item.
+// With ForEachOutputRecover, the panics of the function are recovered and sent to the error channel as PanicErrors.
+// Within a pipeline traced with WithTracer, each item is processed in its own span.
+// The pool of workers can be resized while running with Resizable.
 // ForEachOutput panics if invalid options are provided.
 func ForEachOutput[T, U any](f func(ctx context.Context, val T, out chan<- U, errs chan<- error), opt ...ForEachOutputOption) Pipeline[T, U] {
 	o := mustForEachOutputOptions(opt)
@@ -17,43 +26,447 @@ func ForEachOutput[T, U any](f func(ctx context.Context, val T, out chan<- U, er
 	return func(ctx context.Context, in Stream[T], errs chan<- error) Stream[U] {
 		out := make(chan U, o.bufferSize)
 
+		if describeStage(ctx, TopologyNode{Kind: o.kind, PoolSize: o.poolSize, BufferSize: o.bufferSize}, []any{in}, []any{Stream[U](out)}) {
+			go func() {
+				defer close(out)
+				drain(in)
+			}()
+			return out
+		}
+
+		obs := observeStage(ctx, o.kind)
+		tr := traceStage(ctx, o.kind, in, out)
+		ctrl := claimPoolController(ctx)
+		rate := stageErrorRate(ctx)
+
 		go func() {
+			labelGoroutine(ctx, o.kind, -1)
+
 			defer close(out)
 			defer o.onBeforeClose(ctx)
 
-			wg := sync.WaitGroup{}
-			wg.Add(o.poolSize)
-
-			for i := 0; i < o.poolSize; i++ {
-				go func() {
-					defer wg.Done()
-
-					for {
-						select {
-						case <-ctx.Done():
-							return
-						case v, ok := <-in:
-							if !ok {
-								return
-							}
+			var fOut chan<- U = out
 
-							f(ctx, v, out, errs)
-						}
-					}
+			// The outputs of a traced stage are sent with their span contexts, which also reports them to the observer
+			if obs != nil && tr == nil {
+				// The outputs go through a forwarder, so that the time spent sending them can be measured
+				observed := make(chan U)
+				done := observedForward(ctx, obs, observed, out)
+				defer func() {
+					close(observed)
+					<-done
 				}()
+				fOut = observed
 			}
 
-			wg.Wait()
+			if o.preserveOrder {
+				forEachOutputOrdered(ctx, f, o, obs, tr, ctrl, rate, in, fOut, errs)
+			} else {
+				forEachOutputUnordered(ctx, f, o, obs, tr, ctrl, rate, in, fOut, errs)
+			}
 		}()
 
 		return out
 	}
 }
 
+func forEachOutputUnordered[T, U any](ctx context.Context, f func(context.Context, T, chan<- U, chan<- error), o *forEachOutputOptions, obs StageObserver, tr *stageTracer, ctrl *PoolController, rate *errorRate, in Stream[T], out chan<- U, errs chan<- error) {
+	// The items are numbered while receiving them, so that the index matches their position in the input stream.
+	// The receiving lock is a channel, so that the retiring workers don't wait for it while the input stream is idle.
+	lock := make(chan struct{}, 1)
+	received := 0
+
+	receive := func(retire <-chan struct{}) (v T, index int, parent SpanContext, ok bool) {
+		select {
+		case <-ctx.Done():
+			return v, 0, parent, false
+		case <-retire:
+			return v, 0, parent, false
+		case lock <- struct{}{}:
+		}
+		defer func() { <-lock }()
+
+		if retired(retire) {
+			return v, 0, parent, false
+		}
+
+		start := time.Now()
+
+		select {
+		case <-ctx.Done():
+			return v, 0, parent, false
+		case <-retire:
+			return v, 0, parent, false
+		case v, ok = <-in:
+			if ok && obs != nil {
+				obs.ItemReceived(time.Since(start))
+			}
+			if ok && tr != nil {
+				parent = tr.parent()
+			}
+			index = received
+			received++
+			return v, index, parent, ok
+		}
+	}
+
+	runWorkerPool(ctrl, o.poolSize, func(i int, retire <-chan struct{}) {
+		labelGoroutine(ctx, o.kind, i)
+
+		stageErrs := newStageErrors(ctx, errs, obs, tr != nil || rate != nil)
+		defer stageErrs.close()
+
+		wOut := out
+
+		var tOut *tracedOutput[U]
+		if tr != nil {
+			tOut = newTracedOutput(ctx, tr, obs, out)
+			defer tOut.close()
+			wOut = tOut.items
+		}
+
+		for {
+			v, index, parent, ok := receive(retire)
+			if !ok {
+				return
+			}
+
+			if o.limiter != nil {
+				if err := o.limiter.wait(ctx); err != nil {
+					return
+				}
+			}
+
+			itemCtx := ctx
+
+			var span Span
+			if tr != nil {
+				itemCtx, span = tr.start(ctx, parent)
+				span.SetAttribute("rivo.item.index", index)
+				tOut.setSpan(span.SpanContext())
+			}
+
+			forEachOutputCall(itemCtx, f, o, obs, v, wOut, stageErrs.forItem(index, v))
+			stageErrs.flush()
+
+			if span != nil {
+				span.End(stageErrs.failure())
+			}
+
+			if rate != nil {
+				rate.record(stageErrs.failure() != nil)
+			}
+		}
+	})
+}
+
+// TimeoutError is the error sent to the error channel for an item that was not processed within the item timeout.
+type TimeoutError struct {
+	Item    any
+	Timeout time.Duration
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("item timed out after %v", e.Timeout)
+}
+
+func (e *TimeoutError) Unwrap() error {
+	return context.DeadlineExceeded
+}
+
+// forEachOutputCall applies f to an item, with a deadline if there's an item timeout, recovering its panics if required.
+func forEachOutputCall[T, U any](ctx context.Context, f func(context.Context, T, chan<- U, chan<- error), o *forEachOutputOptions, obs StageObserver, v T, out chan<- U, errs chan<- error) {
+	if obs != nil {
+		start := time.Now()
+		defer func() {
+			obs.ItemProcessed(time.Since(start))
+		}()
+	}
+
+	if o.recoverPanics {
+		defer func() {
+			if r := recover(); r != nil {
+				select {
+				case <-ctx.Done():
+				case errs <- newPanicError(r, v):
+				}
+			}
+		}()
+	}
+
+	if o.itemTimeout == 0 {
+		f(ctx, v, out, errs)
+		return
+	}
+
+	var timedOut bool
+
+	if o.timeoutCalls {
+		d := &itemDeadline{timeout: o.itemTimeout}
+		f(context.WithValue(ctx, itemDeadlineKey{}, d), v, out, errs)
+		timedOut = d.timedOut
+	} else {
+		itemCtx, cancel := context.WithTimeout(ctx, o.itemTimeout)
+		f(itemCtx, v, out, errs)
+		timedOut = errors.Is(itemCtx.Err(), context.DeadlineExceeded)
+		cancel()
+	}
+
+	if timedOut && ctx.Err() == nil {
+		select {
+		case <-ctx.Done():
+		case errs <- &TimeoutError{Item: v, Timeout: o.itemTimeout}:
+		}
+	}
+}
+
+type itemDeadlineKey struct{}
+
+// itemDeadline is the item timeout of an operator configured with forEachOutputTimeoutCalls.
+type itemDeadline struct {
+	timeout  time.Duration
+	timedOut bool
+}
+
+// callContext returns the context of a call to the function of an operator for an item, with the deadline of the item
+// if there's an item timeout, and a function to call once the call has returned. The latter releases the context and
+// reports whether the result must be discarded, since the item has timed out or the pipeline has been stopped.
+func callContext(ctx context.Context) (context.Context, func() bool) {
+	d, _ := ctx.Value(itemDeadlineKey{}).(*itemDeadline)
+	if d == nil {
+		return ctx, func() bool { return ctx.Err() != nil }
+	}
+
+	callCtx, cancel := context.WithTimeout(ctx, d.timeout)
+
+	// The stages run by the function don't inherit the item timeout
+	callCtx = context.WithValue(callCtx, itemDeadlineKey{}, (*itemDeadline)(nil))
+
+	return callCtx, func() bool {
+		d.timedOut = errors.Is(callCtx.Err(), context.DeadlineExceeded)
+		cancel()
+		return d.timedOut || ctx.Err() != nil
+	}
+}
+
+type orderedJob[T, U any] struct {
+	index  int
+	val    T
+	parent SpanContext
+	slot   *orderedSlot[U]
+}
+
+// orderedSlot holds the outputs of an item, with its span context if the stage is traced.
+// The slots are linked in input order: next receives the slot of the following item, and it's closed after the last one.
+type orderedSlot[U any] struct {
+	out  chan U
+	span SpanContext
+	next chan *orderedSlot[U]
+}
+
+// orderedWindow limits how many items can be processed ahead of the oldest item whose outputs are not emitted yet.
+// The limit is read each time an item is dispatched, so that it can follow the size of a resizable pool.
+type orderedWindow struct {
+	limit func() (int, <-chan struct{})
+
+	mu      sync.Mutex
+	pending int
+	freed   chan struct{}
+}
+
+func newOrderedWindow(o *forEachOutputOptions, ctrl *PoolController) *orderedWindow {
+	w := &orderedWindow{freed: make(chan struct{}, 1)}
+
+	switch {
+	case o.maxAhead > 0:
+		w.limit = func() (int, <-chan struct{}) { return o.maxAhead, nil }
+	case ctrl != nil:
+		w.limit = func() (int, <-chan struct{}) {
+			target, resized := ctrl.targetChanges()
+			if target == 0 {
+				// The pool is not attached to the controller yet
+				target = o.poolSize
+			}
+			return 2 * target, resized
+		}
+	default:
+		w.limit = func() (int, <-chan struct{}) { return 2 * o.poolSize, nil }
+	}
+
+	return w
+}
+
+// acquire waits until the next item can be dispatched, returning false if the context is done first.
+// It must not be called concurrently.
+func (w *orderedWindow) acquire(ctx context.Context) bool {
+	for {
+		limit, resized := w.limit()
+
+		w.mu.Lock()
+		// The oldest pending item doesn't count towards the limit
+		if w.pending <= limit {
+			w.pending++
+			w.mu.Unlock()
+			return true
+		}
+		w.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return false
+		case <-w.freed:
+		case <-resized:
+		}
+	}
+}
+
+// release is called once the outputs of the oldest pending item are emitted.
+func (w *orderedWindow) release() {
+	w.mu.Lock()
+	w.pending--
+	w.mu.Unlock()
+
+	select {
+	case w.freed <- struct{}{}:
+	default:
+	}
+}
+
+// forEachOutputOrdered gives each item its own output slot and links the slots in input order.
+// The workers write the outputs of an item to its slot, while the slots are drained one at a time, in order, to the output stream.
+// The number of pending slots is bounded by the orderedWindow, which limits how far ahead of the oldest pending item the workers can run.
+func forEachOutputOrdered[T, U any](ctx context.Context, f func(context.Context, T, chan<- U, chan<- error), o *forEachOutputOptions, obs StageObserver, tr *stageTracer, ctrl *PoolController, rate *errorRate, in Stream[T], out chan<- U, errs chan<- error) {
+	window := newOrderedWindow(o, ctrl)
+
+	jobs := make(chan orderedJob[T, U])
+	head := make(chan *orderedSlot[U], 1)
+
+	go func() {
+		defer close(jobs)
+
+		tail := head
+		defer func() { close(tail) }()
+
+		for index := 0; ; index++ {
+			start := time.Now()
+
+			select {
+			case <-ctx.Done():
+				return
+			case v, ok := <-in:
+				if !ok {
+					return
+				}
+
+				if obs != nil {
+					obs.ItemReceived(time.Since(start))
+				}
+
+				var parent SpanContext
+				if tr != nil {
+					parent = tr.parent()
+				}
+
+				if !window.acquire(ctx) {
+					return
+				}
+
+				slot := &orderedSlot[U]{out: make(chan U, 1), next: make(chan *orderedSlot[U], 1)}
+				tail <- slot
+				tail = slot.next
+
+				select {
+				case <-ctx.Done():
+					close(slot.out)
+					return
+				case jobs <- orderedJob[T, U]{index: index, val: v, parent: parent, slot: slot}:
+				}
+			}
+		}
+	}()
+
+	workersDone := make(chan struct{})
+
+	go func() {
+		defer close(workersDone)
+
+		runWorkerPool(ctrl, o.poolSize, func(i int, retire <-chan struct{}) {
+			labelGoroutine(ctx, o.kind, i)
+
+			stageErrs := newStageErrors(ctx, errs, obs, tr != nil || rate != nil)
+			defer stageErrs.close()
+
+			for !retired(retire) {
+				var job orderedJob[T, U]
+				var ok bool
+
+				select {
+				case <-retire:
+					return
+				case job, ok = <-jobs:
+					if !ok {
+						return
+					}
+				}
+
+				if o.limiter == nil || o.limiter.wait(ctx) == nil {
+					itemCtx := ctx
+
+					var span Span
+					if tr != nil {
+						// The span context is set before any output is written, so it's visible once they are read
+						itemCtx, span = tr.start(ctx, job.parent)
+						span.SetAttribute("rivo.item.index", job.index)
+						job.slot.span = span.SpanContext()
+					}
+
+					forEachOutputCall(itemCtx, f, o, obs, job.val, job.slot.out, stageErrs.forItem(job.index, job.val))
+					stageErrs.flush()
+
+					if span != nil {
+						span.End(stageErrs.failure())
+					}
+
+					if rate != nil {
+						rate.record(stageErrs.failure() != nil)
+					}
+				}
+				close(job.slot.out)
+			}
+		})
+	}()
+
+	for slot, ok := <-head; ok; slot, ok = <-slot.next {
+		for v := range slot.out {
+			if tr != nil {
+				tracedSend(ctx, obs, tr.out, out, v, slot.span)
+				continue
+			}
+
+			// Once the context is done, keep draining the slots, so that no worker is blocked
+			select {
+			case <-ctx.Done():
+			case out <- v:
+			}
+		}
+
+		window.release()
+	}
+
+	<-workersDone
+}
+
 type forEachOutputOptions struct {
 	poolSize      int
 	bufferSize    int
 	onBeforeClose func(context.Context)
+	preserveOrder bool
+	maxAhead      int
+	limiter       *tokenBucket
+	itemTimeout   time.Duration
+	timeoutCalls  bool
+	recoverPanics bool
+	kind          string
 }
 
 type ForEachOutputOption func(*forEachOutputOptions) error
@@ -88,11 +501,112 @@ func ForEachOutputOnBeforeClose(f func(context.Context)) ForEachOutputOption {
 	}
 }
 
+// ForEachOutputPreserveOrder configures ForEachOutput to emit the outputs in the same order as the input items,
+// even when they are processed concurrently by a pool of workers.
+func ForEachOutputPreserveOrder() ForEachOutputOption {
+	return func(o *forEachOutputOptions) error {
+		o.preserveOrder = true
+		return nil
+	}
+}
+
+// ForEachOutputMaxAhead sets how many items can be processed ahead of the oldest item whose outputs are not emitted yet,
+// when the order is preserved. It defaults to twice the pool size, or to twice the size set with the PoolController if
+// the stage is Resizable, and it has no effect without ForEachOutputPreserveOrder.
+func ForEachOutputMaxAhead(maxAhead int) ForEachOutputOption {
+	return func(o *forEachOutputOptions) error {
+		if maxAhead < 1 {
+			return errors.New("maxAhead must be greater than 0")
+		}
+		o.maxAhead = maxAhead
+		return nil
+	}
+}
+
+// ForEachOutputRateLimit limits the rate at which the items are processed to rate items per second, allowing bursts
+// of up to burst items. The limit is shared by all the workers of the pool and by every run of the pipeline;
+// while it's reached, the input stream is not read, so that the upstream stages are blocked. See RateLimit.
+func ForEachOutputRateLimit(rate float64, burst int) ForEachOutputOption {
+	return func(o *forEachOutputOptions) error {
+		if err := validateRateLimit(rate, burst); err != nil {
+			return err
+		}
+		o.limiter = newTokenBucket(rate, burst)
+		return nil
+	}
+}
+
+// ForEachOutputItemTimeout sets how long the function can take to process each item. The function receives a context
+// that is done once the timeout expires and should return as soon as possible: the item is then reported to the
+// error channel with a TimeoutError. The timeout includes the time spent sending the outputs of the item, while for
+// the operators built on ForEachOutput, such as Map, it applies only to the call of their function.
+func ForEachOutputItemTimeout(d time.Duration) ForEachOutputOption {
+	return func(o *forEachOutputOptions) error {
+		if d <= 0 {
+			return errors.New("itemTimeout must be greater than 0")
+		}
+		o.itemTimeout = d
+		return nil
+	}
+}
+
+// ForEachOutputRecover configures ForEachOutput to recover from the panics of the function, sending a PanicError with
+// the item being processed to the error channel and going on with the next items.
+func ForEachOutputRecover() ForEachOutputOption {
+	return func(o *forEachOutputOptions) error {
+		o.recoverPanics = true
+		return nil
+	}
+}
+
+// forEachOutputLimiter configures ForEachOutput to use an existing rate limiter.
+func forEachOutputLimiter(limiter *tokenBucket) ForEachOutputOption {
+	return func(o *forEachOutputOptions) error {
+		o.limiter = limiter
+		return nil
+	}
+}
+
+// forEachOutputChainOnBeforeClose sets a function to be called after the onBeforeClose function already set, if any.
+func forEachOutputChainOnBeforeClose(f func(context.Context)) ForEachOutputOption {
+	return func(o *forEachOutputOptions) error {
+		prev := o.onBeforeClose
+		o.onBeforeClose = func(ctx context.Context) {
+			prev(ctx)
+			f(ctx)
+		}
+		return nil
+	}
+}
+
+// forEachOutputKind sets the operator name of the stage, as reported by Describe.
+func forEachOutputKind(kind string) ForEachOutputOption {
+	return func(o *forEachOutputOptions) error {
+		o.kind = kind
+		return nil
+	}
+}
+
+// forEachOutputTimeoutCalls configures the item timeout to apply only to the calls made with callContext by the
+// function of an operator, such as Map, so that the time spent sending the outputs doesn't count towards it.
+func forEachOutputTimeoutCalls() ForEachOutputOption {
+	return func(o *forEachOutputOptions) error {
+		o.timeoutCalls = true
+		return nil
+	}
+}
+
 func newDefaultForEachOutputOptions() *forEachOutputOptions {
 	return &forEachOutputOptions{
 		poolSize:      1,
 		bufferSize:    0,
 		onBeforeClose: func(ctx context.Context) {},
+		preserveOrder: false,
+		maxAhead:      0,
+		limiter:       nil,
+		itemTimeout:   0,
+		recoverPanics: false,
+		kind:          "ForEachOutput",
 	}
 }
 
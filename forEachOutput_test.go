@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"testing"
+	"time"
 
 	. "github.com/agiac/rivo"
 
@@ -117,4 +118,77 @@ func TestForEachOutput(t *testing.T) {
 
 		assert.ElementsMatch(t, want, got)
 	})
+
+	t.Run("with preserve order", func(t *testing.T) {
+		ctx := context.Background()
+
+		// Each item emits two outputs, earlier items take longer
+		f := func(ctx context.Context, n int, out chan<- int, errs chan<- error) {
+			time.Sleep(time.Duration(10-n) * time.Millisecond)
+			out <- n * 10
+			out <- n*10 + 1
+		}
+
+		in := Of(1, 2, 3, 4, 5)
+
+		fo := ForEachOutput(f, ForEachOutputPoolSize(3), ForEachOutputPreserveOrder())
+
+		got := Collect(Pipe(in, fo)(ctx, nil, nil))
+
+		want := []int{10, 11, 20, 21, 30, 31, 40, 41, 50, 51}
+
+		assert.Equal(t, want, got)
+	})
+
+	t.Run("with preserve order and context cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		f := func(ctx context.Context, n int, out chan<- int, errs chan<- error) {
+			out <- n
+		}
+
+		in := make(chan int)
+		go func() {
+			defer close(in)
+			for i := 0; i < 10; i++ {
+				select {
+				case <-time.After(10 * time.Millisecond):
+					return
+				case in <- i:
+				}
+			}
+		}()
+
+		fo := ForEachOutput(f, ForEachOutputPoolSize(3), ForEachOutputPreserveOrder())
+
+		got := Collect(fo(ctx, in, nil))
+
+		assert.Less(t, len(got), 3)
+	})
+
+	t.Run("with item timeout", func(t *testing.T) {
+		ctx := context.Background()
+
+		// The second item blocks until its deadline
+		f := func(ctx context.Context, n int, out chan<- int, errs chan<- error) {
+			if n == 2 {
+				<-ctx.Done()
+				return
+			}
+			out <- n
+		}
+
+		got, err := RunCollect(ctx, Pipe(Of(1, 2, 3), ForEachOutput(f, ForEachOutputItemTimeout(10*time.Millisecond))))
+
+		assert.Equal(t, []int{1, 3}, got)
+
+		var timeoutErr *TimeoutError
+		if assert.ErrorAs(t, err, &timeoutErr) {
+			assert.Equal(t, 2, timeoutErr.Item)
+			assert.Equal(t, 10*time.Millisecond, timeoutErr.Timeout)
+		}
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.EqualError(t, err, "item timed out after 10ms")
+	})
 }
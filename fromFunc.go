@@ -5,17 +5,29 @@ import (
 	"errors"
 	"fmt"
 	"sync"
+	"time"
 )
 
 // FromFunc returns a Generator that emits items generated by the given function.
 // The returned stream will emit items until the function returns false in the second return value.
+// With FromFuncRecover, the panics of the function are recovered and sent to the error channel as PanicErrors.
 func FromFunc[T any](f func(context.Context) (T, bool, error), options ...FromFuncOption) Generator[T] {
 	o := mustFromFuncOptions(options)
 
 	return func(ctx context.Context, _ Stream[None], errs chan<- error) Stream[T] {
 		out := make(chan T, o.bufferSize)
 
+		if describeStage(ctx, TopologyNode{Kind: "FromFunc", PoolSize: o.poolSize, BufferSize: o.bufferSize}, nil, []any{Stream[T](out)}) {
+			close(out)
+			return out
+		}
+
+		obs := ObserveStage(ctx, "FromFunc")
+		rate := stageErrorRate(ctx)
+
 		go func() {
+			labelGoroutine(ctx, "FromFunc", -1)
+
 			defer close(out)
 			defer o.onBeforeClose(ctx)
 
@@ -24,27 +36,41 @@ func FromFunc[T any](f func(context.Context) (T, bool, error), options ...FromFu
 
 			for i := 0; i < o.poolSize; i++ {
 				go func() {
+					labelGoroutine(ctx, "FromFunc", i)
+
 					defer wg.Done()
 
 					for {
-						v, ok, err := f(ctx)
+						start := time.Now()
+
+						v, ok, err := fromFuncCall(ctx, f, o.recoverPanics)
 						if err != nil {
+							obs.Error()
+
 							select {
 							case <-ctx.Done():
 								return
 							case errs <- fmt.Errorf("FromFunc: %w", err):
-								continue
 							}
+
+							if rate != nil {
+								rate.record(true)
+							}
+							continue
 						}
 
 						if !ok {
 							return
 						}
 
-						select {
-						case <-ctx.Done():
+						if rate != nil {
+							rate.record(false)
+						}
+
+						obs.ItemProcessed(time.Since(start))
+
+						if !observedSend(ctx, obs, out, v) {
 							return
-						case out <- v:
 						}
 					}
 				}()
@@ -57,10 +83,25 @@ func FromFunc[T any](f func(context.Context) (T, bool, error), options ...FromFu
 	}
 }
 
+// fromFuncCall calls f, converting its panics to errors if required.
+func fromFuncCall[T any](ctx context.Context, f func(context.Context) (T, bool, error), recoverPanics bool) (v T, ok bool, err error) {
+	if recoverPanics {
+		defer func() {
+			if r := recover(); r != nil {
+				// Keep going after a panic, as after any other error
+				ok, err = true, newPanicError(r, nil)
+			}
+		}()
+	}
+
+	return f(ctx)
+}
+
 type fromFuncOptions struct {
 	poolSize      int
 	bufferSize    int
 	onBeforeClose func(context.Context)
+	recoverPanics bool
 }
 
 type FromFuncOption func(*fromFuncOptions) error
@@ -95,11 +136,21 @@ func FromFuncOnBeforeClose(f func(context.Context)) FromFuncOption {
 	}
 }
 
+// FromFuncRecover configures FromFunc to recover from the panics of the function, sending a PanicError to the error
+// channel and calling the function again.
+func FromFuncRecover() FromFuncOption {
+	return func(o *fromFuncOptions) error {
+		o.recoverPanics = true
+		return nil
+	}
+}
+
 func newDefaultFromFuncOptions() *fromFuncOptions {
 	return &fromFuncOptions{
 		poolSize:      1,
 		bufferSize:    0,
 		onBeforeClose: func(ctx context.Context) {},
+		recoverPanics: false,
 	}
 }
 
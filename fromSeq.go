@@ -9,6 +9,11 @@ func FromSeq[T any](seq iter.Seq[T]) Generator[T] {
 	return func(ctx context.Context, in Stream[None], errs chan<- error) Stream[T] {
 		out := make(chan T)
 
+		if describeStage(ctx, TopologyNode{Kind: "FromSeq"}, nil, []any{Stream[T](out)}) {
+			close(out)
+			return out
+		}
+
 		go func() {
 			defer close(out)
 
@@ -34,6 +39,11 @@ func FromSeq2[T, U any](seq iter.Seq2[T, U]) Generator[FromSeq2Value[T, U]] {
 	return func(ctx context.Context, in Stream[None], errs chan<- error) Stream[FromSeq2Value[T, U]] {
 		out := make(chan FromSeq2Value[T, U])
 
+		if describeStage(ctx, TopologyNode{Kind: "FromSeq2"}, nil, []any{Stream[FromSeq2Value[T, U]](out)}) {
+			close(out)
+			return out
+		}
+
 		go func() {
 			defer close(out)
 
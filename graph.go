@@ -0,0 +1,333 @@
+package rivo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Graph is a directed acyclic graph of pipelines. Add the nodes with AddNode and connect them with Edge, then use Build
+// or Run to run the graph with a single context and a single error channel.
+// A node with more than one outgoing edge sends a copy of each item to every downstream node, as TeeStreamN does,
+// while a node with more than one incoming edge receives the items of all its upstream nodes, as Merge does.
+// The errors of each node are wrapped in StageErrors carrying its name, as Named does.
+// A Graph is not safe for concurrent use while it's being built.
+type Graph struct {
+	nodes []graphNode
+	names map[string]bool
+	errs  []error
+}
+
+// NewGraph returns an empty Graph.
+func NewGraph() *Graph {
+	return &Graph{names: make(map[string]bool)}
+}
+
+// Node is a node of a Graph, running a pipeline with input type T and output type U.
+type Node[T, U any] struct {
+	n *typedNode[T, U]
+}
+
+// Name returns the name of the node.
+func (n Node[T, U]) Name() string {
+	return n.n.name
+}
+
+// AddNode adds a node with the given name running the given pipeline to the graph.
+// Nodes with input type None and no incoming edges are the sources of the graph; every other node must have at least
+// one incoming edge. Nodes with output type None and no outgoing edges are the sinks of the graph, whose output
+// stream is drained; every other node must have at least one outgoing edge.
+// The names of the nodes must be unique and not empty.
+func AddNode[T, U any](g *Graph, name string, p Pipeline[T, U]) Node[T, U] {
+	_, noInput := any(*new(T)).(None)
+	_, noOutput := any(*new(U)).(None)
+
+	n := &typedNode[T, U]{
+		nodeBase: nodeBase{graph: g, name: name, index: len(g.nodes), noInput: noInput, noOutput: noOutput},
+		p:        p,
+	}
+
+	switch {
+	case name == "":
+		g.errs = append(g.errs, errors.New("node name must not be empty"))
+	case g.names[name]:
+		g.errs = append(g.errs, fmt.Errorf("node %q: name is already used", name))
+	case p == nil:
+		g.errs = append(g.errs, fmt.Errorf("node %q: pipeline is nil", name))
+	default:
+		n.p = Named(name, p)
+	}
+
+	g.names[name] = true
+	g.nodes = append(g.nodes, n)
+
+	return Node[T, U]{n: n}
+}
+
+// Edge connects the output of the node from to the input of the node to.
+func Edge[T, U, V any](from Node[T, U], to Node[U, V]) {
+	g := from.n.graph
+
+	switch {
+	case to.n.graph != g:
+		g.errs = append(g.errs, fmt.Errorf("edge from %q to %q: nodes belong to different graphs", from.n.name, to.n.name))
+	case from.n.hasEdgeTo(to.n.index):
+		g.errs = append(g.errs, fmt.Errorf("edge from %q to %q: duplicate edge", from.n.name, to.n.name))
+	default:
+		from.n.downstream = append(from.n.downstream, to.n.index)
+		to.n.upstream = append(to.n.upstream, from.n.index)
+	}
+}
+
+// Build validates the graph and returns a pipeline that runs it. The input stream of the pipeline is ignored and its
+// output stream is closed once every sink of the graph is done.
+// It returns an error if the graph is empty, has a cycle, or has a node whose input or output is not connected.
+// Changes made to the graph after Build don't affect the returned pipeline.
+func (g *Graph) Build() (Sync[None], error) {
+	if err := g.validate(); err != nil {
+		return nil, err
+	}
+
+	if cycle := g.cycle(); cycle != nil {
+		return nil, fmt.Errorf("graph has a cycle: %s", strings.Join(cycle, " -> "))
+	}
+
+	order := g.topologicalOrder()
+
+	type step struct {
+		node       graphNode
+		downstream []int
+	}
+
+	steps := make([]step, len(order))
+	for i, n := range order {
+		steps[i] = step{node: n, downstream: append([]int(nil), n.base().downstream...)}
+	}
+
+	nodeCount := len(g.nodes)
+
+	return func(ctx context.Context, _ Stream[None], errs chan<- error) Stream[None] {
+		out := make(chan None)
+
+		inputs := make([][]any, nodeCount)
+		var sinks []Stream[None]
+
+		for _, s := range steps {
+			nodeCtx := ctx
+			if !s.node.base().isSource() {
+				nodeCtx = context.WithoutCancel(ctx)
+			}
+
+			streams := s.node.run(nodeCtx, inputs[s.node.base().index], len(s.downstream), errs)
+
+			if len(s.downstream) == 0 {
+				sinks = append(sinks, streams[0].(Stream[None]))
+				continue
+			}
+
+			for i, d := range s.downstream {
+				inputs[d] = append(inputs[d], streams[i])
+			}
+		}
+
+		go func() {
+			defer close(out)
+
+			wg := sync.WaitGroup{}
+			wg.Add(len(sinks))
+
+			for _, s := range sinks {
+				go func() {
+					defer wg.Done()
+					for range s {
+					}
+				}()
+			}
+
+			wg.Wait()
+		}()
+
+		return out
+	}, nil
+}
+
+// Run builds the graph and runs it until every sink is done. It returns the error returned by Build, if any,
+// or otherwise the errors sent by the nodes, as Run does.
+func (g *Graph) Run(ctx context.Context, opt ...RunOption) error {
+	p, err := g.Build()
+	if err != nil {
+		return err
+	}
+
+	return Run(ctx, p, opt...)
+}
+
+func (g *Graph) validate() error {
+	errs := append([]error(nil), g.errs...)
+
+	if len(g.nodes) == 0 {
+		errs = append(errs, errors.New("graph has no nodes"))
+	}
+
+	for _, n := range g.nodes {
+		b := n.base()
+
+		if len(b.upstream) == 0 && !b.noInput {
+			errs = append(errs, fmt.Errorf("node %q: input is not connected", b.name))
+		}
+
+		if len(b.downstream) == 0 && !b.noOutput {
+			errs = append(errs, fmt.Errorf("node %q: output is not consumed", b.name))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// topologicalOrder returns the nodes of an acyclic graph so that each node comes after its upstream nodes.
+func (g *Graph) topologicalOrder() []graphNode {
+	pending := make([]int, len(g.nodes))
+	var ready []int
+
+	for i, n := range g.nodes {
+		pending[i] = len(n.base().upstream)
+		if pending[i] == 0 {
+			ready = append(ready, i)
+		}
+	}
+
+	order := make([]graphNode, 0, len(g.nodes))
+
+	for len(ready) > 0 {
+		i := ready[0]
+		ready = ready[1:]
+
+		order = append(order, g.nodes[i])
+
+		for _, d := range g.nodes[i].base().downstream {
+			pending[d]--
+			if pending[d] == 0 {
+				ready = append(ready, d)
+			}
+		}
+	}
+
+	return order
+}
+
+// cycle returns the names of the nodes of a cycle of the graph, starting and ending with the same node,
+// or nil if the graph is acyclic.
+func (g *Graph) cycle() []string {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+
+	state := make([]int, len(g.nodes))
+	var path []int
+
+	var visit func(i int) []string
+	visit = func(i int) []string {
+		state[i] = visiting
+		path = append(path, i)
+
+		for _, d := range g.nodes[i].base().downstream {
+			switch state[d] {
+			case visiting:
+				var names []string
+				for _, k := range path[slices.Index(path, d):] {
+					names = append(names, g.nodes[k].base().name)
+				}
+				return append(names, g.nodes[d].base().name)
+			case unvisited:
+				if cycle := visit(d); cycle != nil {
+					return cycle
+				}
+			}
+		}
+
+		path = path[:len(path)-1]
+		state[i] = visited
+
+		return nil
+	}
+
+	for i := range g.nodes {
+		if state[i] == unvisited {
+			if cycle := visit(i); cycle != nil {
+				return cycle
+			}
+		}
+	}
+
+	return nil
+}
+
+type graphNode interface {
+	base() *nodeBase
+	// run starts the node reading from the streams of its upstream nodes and returns the streams for its n downstream
+	// nodes, or its output stream if it has none.
+	run(ctx context.Context, in []any, n int, errs chan<- error) []any
+}
+
+type nodeBase struct {
+	graph      *Graph
+	name       string
+	index      int
+	noInput    bool // the input type is None
+	noOutput   bool // the output type is None
+	upstream   []int
+	downstream []int
+}
+
+func (b *nodeBase) base() *nodeBase {
+	return b
+}
+
+func (b *nodeBase) isSource() bool {
+	return len(b.upstream) == 0
+}
+
+func (b *nodeBase) hasEdgeTo(index int) bool {
+	return slices.Contains(b.downstream, index)
+}
+
+type typedNode[T, U any] struct {
+	nodeBase
+	p Pipeline[T, U]
+}
+
+func (n *typedNode[T, U]) run(ctx context.Context, in []any, count int, errs chan<- error) []any {
+	var input Stream[T]
+
+	switch len(in) {
+	case 0:
+	case 1:
+		input = in[0].(Stream[T])
+	default:
+		streams := make([]<-chan T, len(in))
+		for i, s := range in {
+			streams[i] = s.(Stream[T])
+		}
+		input = Merge(ctx, streams...)
+	}
+
+	output := n.p(ctx, input, errs)
+
+	if count <= 1 {
+		return []any{output}
+	}
+
+	streams := TeeStreamN(ctx, output, count)
+
+	outputs := make([]any, count)
+	for i, s := range streams {
+		outputs[i] = s
+	}
+
+	return outputs
+}
@@ -0,0 +1,247 @@
+package rivo_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/agiac/rivo"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func ExampleGraph() {
+	ctx := context.Background()
+
+	g := NewGraph()
+
+	numbers := AddNode(g, "numbers", Of(1, 2, 3, 4, 5))
+
+	even := AddNode(g, "even", Filter(func(ctx context.Context, n int) (bool, error) {
+		return n%2 == 0, nil
+	}))
+
+	odd := AddNode(g, "odd", Filter(func(ctx context.Context, n int) (bool, error) {
+		return n%2 == 1, nil
+	}))
+
+	half := AddNode(g, "half", Map(func(ctx context.Context, n int) (int, error) {
+		return n / 2, nil
+	}))
+
+	triple := AddNode(g, "triple", Map(func(ctx context.Context, n int) (int, error) {
+		return 3*n + 1, nil
+	}))
+
+	var got []int
+	collect := AddNode(g, "collect", Do(func(ctx context.Context, n int) error {
+		got = append(got, n)
+		return nil
+	}))
+
+	Edge(numbers, even)
+	Edge(numbers, odd)
+	Edge(even, half)
+	Edge(odd, triple)
+	Edge(half, collect)
+	Edge(triple, collect)
+
+	if err := g.Run(ctx); err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	slices.Sort(got)
+	fmt.Println(got)
+
+	// Output:
+	// [1 2 4 10 16]
+}
+
+func TestGraph(t *testing.T) {
+	double := Map(func(ctx context.Context, n int) (int, error) {
+		return n * 2, nil
+	})
+
+	collector := func() (Sync[int], func() []int) {
+		var mu sync.Mutex
+		var got []int
+
+		sink := Do(func(ctx context.Context, n int) error {
+			mu.Lock()
+			defer mu.Unlock()
+			got = append(got, n)
+			return nil
+		})
+
+		return sink, func() []int {
+			mu.Lock()
+			defer mu.Unlock()
+			slices.Sort(got)
+			return got
+		}
+	}
+
+	t.Run("fan-out and fan-in", func(t *testing.T) {
+		ctx := context.Background()
+
+		g := NewGraph()
+
+		sinkA, gotA := collector()
+		sinkB, gotB := collector()
+
+		a := AddNode(g, "a", Of(1, 2))
+		b := AddNode(g, "b", Of(10, 20))
+		d := AddNode(g, "double", double)
+		c1 := AddNode(g, "collect 1", sinkA)
+		c2 := AddNode(g, "collect 2", sinkB)
+
+		Edge(a, d)
+		Edge(b, d)
+		Edge(d, c1)
+		Edge(d, c2)
+
+		assert.NoError(t, g.Run(ctx))
+		assert.Equal(t, []int{2, 4, 20, 40}, gotA())
+		assert.Equal(t, []int{2, 4, 20, 40}, gotB())
+	})
+
+	t.Run("build once and run many times", func(t *testing.T) {
+		ctx := context.Background()
+
+		g := NewGraph()
+
+		sink, got := collector()
+
+		Edge(AddNode(g, "numbers", Of(1, 2, 3)), AddNode(g, "collect", sink))
+
+		p, err := g.Build()
+		assert.NoError(t, err)
+
+		assert.NoError(t, Run(ctx, p))
+		assert.NoError(t, Run(ctx, p))
+		assert.Equal(t, []int{1, 1, 2, 2, 3, 3}, got())
+	})
+
+	t.Run("errors are named after the nodes", func(t *testing.T) {
+		ctx := context.Background()
+
+		errOdd := errors.New("odd")
+
+		g := NewGraph()
+
+		sink, got := collector()
+
+		numbers := AddNode(g, "numbers", Of(1, 2, 3, 4))
+		even := AddNode(g, "even", Map(func(ctx context.Context, n int) (int, error) {
+			if n%2 == 1 {
+				return 0, errOdd
+			}
+			return n, nil
+		}))
+		collect := AddNode(g, "collect", sink)
+
+		Edge(numbers, even)
+		Edge(even, collect)
+
+		err := g.Run(ctx)
+
+		assert.Equal(t, []int{2, 4}, got())
+		assert.EqualError(t, err, "even: item 0: odd\neven: item 2: odd")
+		assert.ErrorIs(t, err, errOdd)
+	})
+
+	t.Run("context cancellation", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		g := NewGraph()
+
+		sink, got := collector()
+
+		numbers := AddNode(g, "numbers", FromFunc(func(ctx context.Context) (int, bool, error) {
+			return 1, true, nil
+		}))
+		collect := AddNode(g, "collect", sink)
+
+		Edge(numbers, collect)
+
+		done := make(chan error)
+		go func() {
+			done <- g.Run(ctx)
+		}()
+
+		select {
+		case err := <-done:
+			assert.NoError(t, err)
+		case <-time.After(time.Second):
+			assert.FailNow(t, "the graph should stop once the context is done")
+		}
+
+		// The endless source stops once the context is done, although it may still emit a few items before noticing
+		assert.Less(t, len(got()), 100)
+	})
+
+	t.Run("invalid topology", func(t *testing.T) {
+		sink, _ := collector()
+
+		g := NewGraph()
+		_, err := g.Build()
+		assert.EqualError(t, err, "graph has no nodes")
+
+		g = NewGraph()
+		AddNode(g, "numbers", Of(1, 2, 3))
+		AddNode(g, "double", double)
+		AddNode(g, "collect", sink)
+		_, err = g.Build()
+		assert.EqualError(t, err, "node \"numbers\": output is not consumed\n"+
+			"node \"double\": input is not connected\n"+
+			"node \"double\": output is not consumed\n"+
+			"node \"collect\": input is not connected")
+
+		g = NewGraph()
+		numbers := AddNode(g, "numbers", Of(1, 2, 3))
+		a := AddNode(g, "a", double)
+		b := AddNode(g, "b", double)
+		c := AddNode(g, "c", double)
+		collect := AddNode(g, "collect", sink)
+		Edge(numbers, a)
+		Edge(a, b)
+		Edge(b, c)
+		Edge(c, a)
+		Edge(c, collect)
+		_, err = g.Build()
+		assert.EqualError(t, err, "graph has a cycle: a -> b -> c -> a")
+	})
+
+	t.Run("invalid nodes and edges", func(t *testing.T) {
+		sink, _ := collector()
+
+		g := NewGraph()
+		numbers := AddNode(g, "numbers", Of(1, 2, 3))
+		AddNode(g, "", double)
+		collect := AddNode(g, "collect", sink)
+		other := AddNode(NewGraph(), "other", sink)
+		Edge(numbers, collect)
+		Edge(numbers, collect)
+		Edge(numbers, other)
+		AddNode(g, "collect", sink)
+		AddNode[int, int](g, "nil", nil)
+
+		_, err := g.Build()
+		assert.EqualError(t, err, "node name must not be empty\n"+
+			"edge from \"numbers\" to \"collect\": duplicate edge\n"+
+			"edge from \"numbers\" to \"other\": nodes belong to different graphs\n"+
+			"node \"collect\": name is already used\n"+
+			"node \"nil\": pipeline is nil\n"+
+			"node \"\": input is not connected\n"+
+			"node \"\": output is not consumed\n"+
+			"node \"collect\": input is not connected\n"+
+			"node \"nil\": input is not connected\n"+
+			"node \"nil\": output is not consumed")
+	})
+}
@@ -0,0 +1,222 @@
+package rivo
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Grouped is an item emitted by the pipeline of a group, together with the key of the group.
+type Grouped[K comparable, T any] struct {
+	Key K
+	Val T
+}
+
+// GroupBy returns a pipeline that splits the input stream in groups according to the key of each item.
+// For each new key, it lazily creates the pipeline of the group by calling the given function and feeds it with the
+// items of the group. The outputs of all the groups are merged together, each one with the key of its group.
+// A group is closed, and its pipeline torn down, when the input stream is closed, when it's idle for longer than
+// the timeout set with GroupByIdleTimeout, or when it's the least recently used group and a new group would
+// exceed the limit set with GroupByMaxGroups. If an item with the key of a closed group arrives, a new group is created.
+// GroupBy panics if invalid options are provided.
+func GroupBy[T any, K comparable, U any](key func(T) K, group func(key K) Pipeline[T, U], opt ...GroupByOption) Pipeline[T, Grouped[K, U]] {
+	o := assertGroupByOptions(opt)
+
+	return func(ctx context.Context, in Stream[T], errs chan<- error) Stream[Grouped[K, U]] {
+		out := make(chan Grouped[K, U], o.bufferSize)
+
+		// The pipelines of the groups are created lazily, so they can't be described
+		if DescribeStage(ctx, TopologyNode{Kind: "GroupBy", BufferSize: o.bufferSize}, in, out) {
+			return out
+		}
+
+		go func() {
+			defer close(out)
+
+			groupsCtx := downstreamContext(ctx)
+
+			type groupState struct {
+				in       chan T
+				lastSeen time.Time
+			}
+
+			groups := make(map[K]*groupState)
+			wg := sync.WaitGroup{}
+
+			closeGroup := func(k K) {
+				close(groups[k].in)
+				delete(groups, k)
+			}
+
+			closeLeastRecentlyUsed := func() {
+				var lru K
+				var lruSeen time.Time
+				first := true
+
+				for k, g := range groups {
+					if first || g.lastSeen.Before(lruSeen) {
+						lru, lruSeen, first = k, g.lastSeen, false
+					}
+				}
+
+				closeGroup(lru)
+			}
+
+			openGroup := func(k K) *groupState {
+				if o.maxGroups > 0 && len(groups) >= o.maxGroups {
+					closeLeastRecentlyUsed()
+				}
+
+				g := &groupState{in: make(chan T, o.groupBufferSize)}
+				groups[k] = g
+
+				// The pipeline of the group is wired while the others are running
+				groupOut := wireTraced(groupsCtx, func(ctx context.Context) Stream[U] {
+					return group(k)(ctx, g.in, errs)
+				})
+
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					// Once the context is cancelled, the outputs are discarded, so that the group can be torn down
+					for v := range groupOut {
+						select {
+						case <-ctx.Done():
+						case out <- Grouped[K, U]{Key: k, Val: v}:
+						}
+					}
+				}()
+
+				return g
+			}
+
+			defer func() {
+				for k := range groups {
+					closeGroup(k)
+				}
+				wg.Wait()
+			}()
+
+			var idleTick <-chan time.Time
+			if o.idleTimeout > 0 {
+				ticker := time.NewTicker(o.idleTimeout)
+				defer ticker.Stop()
+				idleTick = ticker.C
+			}
+
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case item, ok := <-in:
+					if !ok {
+						return
+					}
+
+					k := key(item)
+
+					g, ok := groups[k]
+					if !ok {
+						g = openGroup(k)
+					}
+
+					g.lastSeen = time.Now()
+
+					select {
+					case <-ctx.Done():
+						return
+					case g.in <- item:
+					}
+				case now := <-idleTick:
+					for k, g := range groups {
+						if now.Sub(g.lastSeen) >= o.idleTimeout {
+							closeGroup(k)
+						}
+					}
+				}
+			}
+		}()
+
+		return out
+	}
+}
+
+type groupByOptions struct {
+	idleTimeout     time.Duration
+	maxGroups       int
+	groupBufferSize int
+	bufferSize      int
+}
+
+type GroupByOption func(*groupByOptions) error
+
+// GroupByIdleTimeout configures GroupBy to close the groups that receive no items for at least d.
+func GroupByIdleTimeout(d time.Duration) GroupByOption {
+	return func(o *groupByOptions) error {
+		if d <= 0 {
+			return fmt.Errorf("idleTimeout must be greater than 0")
+		}
+		o.idleTimeout = d
+		return nil
+	}
+}
+
+// GroupByMaxGroups limits the number of open groups: when a new group would exceed it, the least recently used group is closed.
+func GroupByMaxGroups(n int) GroupByOption {
+	return func(o *groupByOptions) error {
+		if n < 1 {
+			return fmt.Errorf("maxGroups must be greater than 0")
+		}
+		o.maxGroups = n
+		return nil
+	}
+}
+
+// GroupByGroupBufferSize sets the buffer size of the input stream of each group.
+func GroupByGroupBufferSize(n int) GroupByOption {
+	return func(o *groupByOptions) error {
+		if n < 0 {
+			return fmt.Errorf("groupBufferSize must be greater than or equal to 0")
+		}
+		o.groupBufferSize = n
+		return nil
+	}
+}
+
+func GroupByBufferSize(n int) GroupByOption {
+	return func(o *groupByOptions) error {
+		if n < 0 {
+			return fmt.Errorf("bufferSize must be greater than or equal to 0")
+		}
+		o.bufferSize = n
+		return nil
+	}
+}
+
+func newDefaultGroupByOptions() *groupByOptions {
+	return &groupByOptions{
+		idleTimeout:     0,
+		maxGroups:       0,
+		groupBufferSize: 0,
+		bufferSize:      0,
+	}
+}
+
+func applyGroupByOptions(opt []GroupByOption) (*groupByOptions, error) {
+	opts := newDefaultGroupByOptions()
+	for _, o := range opt {
+		if err := o(opts); err != nil {
+			return opts, err
+		}
+	}
+	return opts, nil
+}
+
+func assertGroupByOptions(opt []GroupByOption) *groupByOptions {
+	opts, err := applyGroupByOptions(opt)
+	if err != nil {
+		panic(fmt.Errorf("invalid groupBy options: %v", err))
+	}
+	return opts
+}
@@ -0,0 +1,198 @@
+package rivo_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/agiac/rivo"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func ExampleGroupBy() {
+	ctx := context.Background()
+
+	in := Of("apple", "avocado", "banana", "blueberry", "cherry", "apricot")
+
+	// Join the words of each group
+	join := func(letter byte) Pipeline[string, string] {
+		return Fold("", func(ctx context.Context, acc string, s string) (string, error) {
+			return strings.TrimSpace(acc + " " + s), nil
+		})
+	}
+
+	p := GroupBy(func(s string) byte { return s[0] }, join)
+
+	got := Collect(Pipe(in, p)(ctx, nil, nil))
+
+	slices.SortFunc(got, func(a, b Grouped[byte, string]) int {
+		return int(a.Key) - int(b.Key)
+	})
+
+	for _, g := range got {
+		fmt.Printf("%c: %s\n", g.Key, g.Val)
+	}
+
+	// Output:
+	// a: apple avocado apricot
+	// b: banana blueberry
+	// c: cherry
+}
+
+func TestGroupBy(t *testing.T) {
+	count := func(key string) Pipeline[string, int] {
+		return Fold(0, func(ctx context.Context, acc int, s string) (int, error) {
+			return acc + 1, nil
+		})
+	}
+
+	first := func(s string) string { return s[:1] }
+
+	t.Run("group items by key", func(t *testing.T) {
+		ctx := context.Background()
+
+		var mu sync.Mutex
+		var created []string
+
+		group := func(key string) Pipeline[string, int] {
+			mu.Lock()
+			created = append(created, key)
+			mu.Unlock()
+			return count(key)
+		}
+
+		got := Collect(Pipe(Of("a1", "b1", "a2", "c1", "b2", "a3"), GroupBy(first, group))(ctx, nil, nil))
+
+		want := []Grouped[string, int]{{Key: "a", Val: 3}, {Key: "b", Val: 2}, {Key: "c", Val: 1}}
+
+		assert.ElementsMatch(t, want, got)
+		assert.Equal(t, []string{"a", "b", "c"}, created)
+	})
+
+	t.Run("with max groups", func(t *testing.T) {
+		ctx := context.Background()
+
+		// Opening c closes a, the least recently used group, so a second group is created for a3
+		in := Of("a1", "b1", "a2", "b2", "c1", "a3")
+
+		got := Collect(Pipe(in, GroupBy(first, count, GroupByMaxGroups(2)))(ctx, nil, nil))
+
+		want := []Grouped[string, int]{{Key: "a", Val: 2}, {Key: "b", Val: 2}, {Key: "c", Val: 1}, {Key: "a", Val: 1}}
+
+		assert.ElementsMatch(t, want, got)
+	})
+
+	t.Run("with idle timeout", func(t *testing.T) {
+		ctx := context.Background()
+
+		in := make(chan string)
+
+		out := GroupBy(first, count, GroupByIdleTimeout(20*time.Millisecond))(ctx, in, nil)
+
+		in <- "a1"
+		in <- "a2"
+
+		// The group is closed after being idle, so its result is emitted before the input is closed
+		select {
+		case g := <-out:
+			assert.Equal(t, Grouped[string, int]{Key: "a", Val: 2}, g)
+		case <-time.After(time.Second):
+			assert.Fail(t, "the idle group should have been closed")
+		}
+
+		in <- "a3"
+		close(in)
+
+		assert.Equal(t, []Grouped[string, int]{{Key: "a", Val: 1}}, Collect(out))
+	})
+
+	t.Run("errors from groups", func(t *testing.T) {
+		ctx := context.Background()
+
+		fail := func(key string) Pipeline[string, int] {
+			return Map(func(ctx context.Context, s string) (int, error) {
+				return 0, errors.New("error on " + s)
+			})
+		}
+
+		got, err := RunCollect(ctx, Pipe(Of("a1", "b1"), GroupBy(first, fail)))
+
+		assert.Empty(t, got)
+		assert.ErrorContains(t, err, "error on a1")
+		assert.ErrorContains(t, err, "error on b1")
+	})
+
+	t.Run("context cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		in := make(chan string)
+
+		got := Collect(GroupBy(first, count)(ctx, in, nil))
+
+		assert.Empty(t, got)
+	})
+
+	t.Run("context cancelled while the output is not consumed", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		received := make(chan struct{})
+		flushed := make(chan struct{})
+		echo := func(key string) Pipeline[string, string] {
+			return ForEachOutput(func(ctx context.Context, s string, out chan<- string, errs chan<- error) {
+				close(received)
+				out <- s
+				out <- s
+			}, ForEachOutputOnBeforeClose(func(ctx context.Context) {
+				close(flushed)
+			}))
+		}
+
+		in := make(chan string)
+		out := GroupBy(first, echo)(ctx, in, nil)
+
+		in <- "a1"
+		<-received
+		cancel()
+
+		// The group is torn down even if its output is never consumed
+		select {
+		case <-flushed:
+		case <-time.After(time.Second):
+			assert.Fail(t, "the group should have been torn down")
+		}
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			Collect(out)
+		}()
+
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			assert.Fail(t, "the output should have been closed")
+		}
+	})
+
+	t.Run("with buffer size", func(t *testing.T) {
+		ctx := context.Background()
+
+		out := GroupBy(first, count, GroupByBufferSize(2), GroupByGroupBufferSize(2))(ctx, Of("a1")(ctx, nil, nil), nil)
+
+		assert.Equal(t, 2, cap(out))
+		assert.Equal(t, []Grouped[string, int]{{Key: "a", Val: 1}}, Collect(out))
+	})
+
+	t.Run("invalid options", func(t *testing.T) {
+		assert.Panics(t, func() { GroupBy(first, count, GroupByMaxGroups(0)) })
+		assert.Panics(t, func() { GroupBy(first, count, GroupByIdleTimeout(0)) })
+	})
+}
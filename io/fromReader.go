@@ -2,28 +2,39 @@ package io
 
 import (
 	"context"
-	"github.com/agiac/rivo"
 	"io"
+	"time"
+
+	"github.com/agiac/rivo"
 )
 
 // TODO: consider using ForEachOutput function
 
 // FromReader returns a pipeline that reads from an io.Reader.
 func FromReader(r io.Reader) rivo.Pipeline[rivo.None, []byte] {
-	return func(ctx context.Context, _ rivo.Stream[rivo.None], errs chan<- error) rivo.Stream[[]byte] {
+	return func(ctx context.Context, in rivo.Stream[rivo.None], errs chan<- error) rivo.Stream[[]byte] {
 		out := make(chan []byte)
 
+		if rivo.DescribeStage(ctx, rivo.TopologyNode{Kind: "io.FromReader"}, in, out) {
+			return out
+		}
+
+		obs := rivo.ObserveStage(ctx, "io.FromReader")
+
 		go func() {
 			defer close(out)
 
 			buf := make([]byte, 1024)
 
 			for {
+				start := time.Now()
+
 				n, err := r.Read(buf)
 				if err != nil {
 					if err == io.EOF {
 						return
 					}
+					obs.Error()
 					select {
 					case <-ctx.Done():
 					case errs <- err:
@@ -34,10 +45,15 @@ func FromReader(r io.Reader) rivo.Pipeline[rivo.None, []byte] {
 				val := make([]byte, n)
 				copy(val, buf[:n])
 
+				obs.ItemProcessed(time.Since(start))
+
+				start = time.Now()
+
 				select {
 				case <-ctx.Done():
 					return
 				case out <- val:
+					obs.ItemSent(time.Since(start), len(out), cap(out))
 				}
 			}
 		}()
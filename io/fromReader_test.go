@@ -5,6 +5,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/agiac/rivo"
 	. "github.com/agiac/rivo/io"
 	"github.com/stretchr/testify/assert"
 )
@@ -37,4 +38,28 @@ func TestFromReader(t *testing.T) {
 		assert.Equal(t, s, got)
 	})
 
+	t.Run("observer", func(t *testing.T) {
+		ctx := context.Background()
+
+		obs := rivo.NewMemoryObserver()
+
+		s := strings.Repeat("Hello World", 1000)
+		g := rivo.WithObserver(obs, FromReader(strings.NewReader(s)))
+
+		got, err := rivo.RunCollect(ctx, g)
+		assert.NoError(t, err)
+
+		m := obs.Snapshot()["io.FromReader"]
+		assert.Equal(t, int64(len(got)), m.ItemsOut)
+		assert.Equal(t, int64(len(got)), m.Latency.Count)
+	})
+
+	t.Run("describe", func(t *testing.T) {
+		r := strings.NewReader("Hello World")
+
+		got := rivo.Describe(FromReader(r))
+
+		assert.Equal(t, []rivo.TopologyNode{{ID: 0, Kind: "io.FromReader"}, {ID: 1, Kind: "output"}}, got.Nodes)
+		assert.Equal(t, 11, r.Len(), "the reader should not be read")
+	})
 }
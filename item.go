@@ -0,0 +1,143 @@
+package rivo
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Item is a value of type T paired with the error, if any, that occurred while producing it.
+// Streams of items carry the errors in-band, alongside the values, instead of sending them to the error channel.
+type Item[T any] struct {
+	Val T
+	Err error
+}
+
+// ItemError is the error of an Item whose value could not be processed by one of the Item pipelines.
+// It carries the original value, so that failed records can be routed together with their payload.
+type ItemError struct {
+	Item any
+	Err  error
+}
+
+func (e *ItemError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ItemError) Unwrap() error {
+	return e.Err
+}
+
+// itemCall applies the retry, item timeout and panic recovery options of an Item operator to the calls of its
+// function only, rather than to the whole item, so that its failures are reported with the item they belong to.
+type itemCall struct {
+	retry         *retryOptions
+	timeout       time.Duration
+	recoverPanics bool
+}
+
+// do calls attempt for the given value and returns an ItemError carrying the value if it fails, times out or panics.
+func (c itemCall) do(ctx context.Context, val any, attempt func(context.Context) error) (err error) {
+	defer func() {
+		if c.recoverPanics {
+			if r := recover(); r != nil {
+				err = newPanicError(r, val)
+			}
+		}
+
+		if err != nil {
+			err = &ItemError{Item: val, Err: err}
+		}
+	}()
+
+	callCtx := ctx
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		callCtx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+
+	if c.retry != nil {
+		err = c.retry.do(callCtx, attempt)
+	} else {
+		err = attempt(callCtx)
+	}
+
+	if errors.Is(callCtx.Err(), context.DeadlineExceeded) && ctx.Err() == nil {
+		err = &TimeoutError{Item: val, Timeout: c.timeout}
+	}
+
+	return err
+}
+
+// ToItems returns a pipeline that wraps each value of the input stream in an Item.
+func ToItems[T any]() Pipeline[T, Item[T]] {
+	return ForEachOutput[T, Item[T]](func(ctx context.Context, val T, out chan<- Item[T], errs chan<- error) {
+		select {
+		case <-ctx.Done():
+			return
+		case out <- Item[T]{Val: val}:
+		}
+	}, forEachOutputKind("ToItems"))
+}
+
+// FilterMapValues returns a pipeline that emits the values of the successful items of the input stream and discards the failed ones.
+func FilterMapValues[T any]() Pipeline[Item[T], T] {
+	return FilterMap(func(ctx context.Context, item Item[T]) (bool, T, error) {
+		return item.Err == nil, item.Val, nil
+	})
+}
+
+// FilterMapErrors returns a pipeline that emits the errors of the failed items of the input stream and discards the successful ones.
+func FilterMapErrors[T any]() Pipeline[Item[T], error] {
+	return FilterMap(func(ctx context.Context, item Item[T]) (bool, error, error) {
+		return item.Err != nil, item.Err, nil
+	})
+}
+
+// UnwrapItems returns a pipeline that emits the values of the successful items of the input stream
+// and sends the errors of the failed ones to the error channel.
+func UnwrapItems[T any]() Pipeline[Item[T], T] {
+	return Map(func(ctx context.Context, item Item[T]) (T, error) {
+		return item.Val, item.Err
+	})
+}
+
+// SplitItems takes an input stream of items and returns two generators:
+// one emitting the values of the successful items and another emitting the errors of the failed ones.
+// Both generators must be consumed, otherwise the input stream is blocked.
+func SplitItems[T any](ctx context.Context, in Stream[Item[T]]) (Generator[T], Generator[error]) {
+	vals := make(chan T)
+	errs := make(chan error)
+
+	go func() {
+		defer close(vals)
+		defer close(errs)
+
+		for item := range OrDone(ctx, in) {
+			if item.Err != nil {
+				select {
+				case <-ctx.Done():
+					return
+				case errs <- item.Err:
+				}
+			} else {
+				select {
+				case <-ctx.Done():
+					return
+				case vals <- item.Val:
+				}
+			}
+		}
+	}()
+
+	valsGen := func(ctx context.Context, _ Stream[None], _ chan<- error) Stream[T] {
+		return vals
+	}
+
+	errsGen := func(ctx context.Context, _ Stream[None], _ chan<- error) Stream[error] {
+		return errs
+	}
+
+	return valsGen, errsGen
+}
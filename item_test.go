@@ -0,0 +1,148 @@
+package rivo_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+
+	. "github.com/agiac/rivo"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func ExampleItem() {
+	ctx := context.Background()
+
+	g := Pipe(Of("1", "2", "invalid", "4"), ToItems[string]())
+
+	toInt := MapItems(func(ctx context.Context, s string) (int, error) {
+		return strconv.Atoi(s)
+	})
+
+	for item := range Pipe(g, toInt)(ctx, nil, nil) {
+		var itemErr *ItemError
+		if errors.As(item.Err, &itemErr) {
+			fmt.Printf("ERROR: %q: %v\n", itemErr.Item, itemErr.Err)
+			continue
+		}
+
+		fmt.Println(item.Val)
+	}
+
+	// Output:
+	// 1
+	// 2
+	// ERROR: "invalid": strconv.Atoi: parsing "invalid": invalid syntax
+	// 4
+}
+
+func TestToItems(t *testing.T) {
+	ctx := context.Background()
+
+	got := Collect(Pipe(Of(1, 2, 3), ToItems[int]())(ctx, nil, nil))
+
+	want := []Item[int]{{Val: 1}, {Val: 2}, {Val: 3}}
+
+	assert.Equal(t, want, got)
+}
+
+func TestFilterMapValues(t *testing.T) {
+	ctx := context.Background()
+
+	err := errors.New("error")
+
+	got := Collect(Pipe(Of(Item[int]{Val: 1}, Item[int]{Err: err}, Item[int]{Val: 3}), FilterMapValues[int]())(ctx, nil, nil))
+
+	assert.Equal(t, []int{1, 3}, got)
+}
+
+func TestFilterMapErrors(t *testing.T) {
+	ctx := context.Background()
+
+	err := errors.New("error")
+
+	got := Collect(Pipe(Of(Item[int]{Val: 1}, Item[int]{Err: err}, Item[int]{Val: 3}), FilterMapErrors[int]())(ctx, nil, nil))
+
+	assert.Equal(t, []error{err}, got)
+}
+
+func TestUnwrapItems(t *testing.T) {
+	ctx := context.Background()
+
+	err := errors.New("error")
+
+	g := Of(Item[int]{Val: 1}, Item[int]{Err: err}, Item[int]{Val: 3})
+
+	got, runErr := RunCollect(ctx, Pipe(g, UnwrapItems[int]()))
+
+	assert.Equal(t, []int{1, 3}, got)
+	assert.ErrorIs(t, runErr, err)
+}
+
+func TestSplitItems(t *testing.T) {
+	t.Run("split values and errors", func(t *testing.T) {
+		ctx := context.Background()
+
+		err1 := errors.New("error 1")
+		err2 := errors.New("error 2")
+
+		in := Of(Item[int]{Val: 1}, Item[int]{Err: err1}, Item[int]{Val: 3}, Item[int]{Err: err2})(ctx, nil, nil)
+
+		vals, errs := SplitItems(ctx, in)
+
+		var gotErrs []error
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			gotErrs = Collect(errs(ctx, nil, nil))
+		}()
+
+		gotVals := Collect(vals(ctx, nil, nil))
+		<-done
+
+		assert.Equal(t, []int{1, 3}, gotVals)
+		assert.Equal(t, []error{err1, err2}, gotErrs)
+	})
+
+	t.Run("context cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		in := make(chan Item[int])
+
+		vals, errs := SplitItems(ctx, in)
+
+		assert.Empty(t, Collect(vals(ctx, nil, nil)))
+		assert.Empty(t, Collect(errs(ctx, nil, nil)))
+	})
+
+	t.Run("context cancelled while a generator is not consumed", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		in := make(chan Item[int], 1)
+		in <- Item[int]{Err: errors.New("error")}
+
+		vals, _ := SplitItems(ctx, in)
+
+		go func() {
+			time.Sleep(10 * time.Millisecond)
+			cancel()
+		}()
+
+		// The errors are never consumed, so the split stops when the context is cancelled
+		assert.Empty(t, Collect(vals(ctx, nil, nil)))
+	})
+}
+
+func TestItemError(t *testing.T) {
+	err := errors.New("error")
+
+	itemErr := &ItemError{Item: "a", Err: fmt.Errorf("wrapped: %w", err)}
+
+	assert.EqualError(t, itemErr, "wrapped: error")
+	assert.ErrorIs(t, itemErr, err)
+}
@@ -3,15 +3,25 @@ package rivo
 import (
 	"context"
 	"fmt"
+	"time"
 )
 
 // Map returns a pipeline that applies a function to each item from the input stream.
 func Map[T, U any](f func(context.Context, T) (U, error), opt ...MapOption) Pipeline[T, U] {
 	o := mustMapOptions(opt)
 
+	if o.retry != nil {
+		f = retryFunc(o.retry, f)
+	}
+
 	return ForEachOutput[T, U](
 		func(ctx context.Context, val T, out chan<- U, errs chan<- error) {
-			v, err := f(ctx, val)
+			callCtx, discard := callContext(ctx)
+			v, err := f(callCtx, val)
+			if discard() {
+				return
+			}
+
 			if err != nil {
 				select {
 				case <-ctx.Done():
@@ -27,14 +37,50 @@ func Map[T, U any](f func(context.Context, T) (U, error), opt ...MapOption) Pipe
 			case out <- v:
 			}
 		},
-		ForEachOutputPoolSize(o.poolSize),
-		ForEachOutputBufferSize(o.bufferSize),
+		o.forEachOutputOptions()...,
 	)
 }
 
 type mapOptions struct {
-	poolSize   int
-	bufferSize int
+	poolSize      int
+	bufferSize    int
+	preserveOrder bool
+	maxAhead      int
+	limiter       *tokenBucket
+	retry         *retryOptions
+	itemTimeout   time.Duration
+	recoverPanics bool
+}
+
+func (o *mapOptions) forEachOutputOptions() []ForEachOutputOption {
+	opts := []ForEachOutputOption{
+		ForEachOutputPoolSize(o.poolSize),
+		ForEachOutputBufferSize(o.bufferSize),
+		forEachOutputKind("Map"),
+		forEachOutputTimeoutCalls(),
+	}
+
+	if o.preserveOrder {
+		opts = append(opts, ForEachOutputPreserveOrder())
+	}
+
+	if o.maxAhead > 0 {
+		opts = append(opts, ForEachOutputMaxAhead(o.maxAhead))
+	}
+
+	if o.limiter != nil {
+		opts = append(opts, forEachOutputLimiter(o.limiter))
+	}
+
+	if o.itemTimeout > 0 {
+		opts = append(opts, ForEachOutputItemTimeout(o.itemTimeout))
+	}
+
+	if o.recoverPanics {
+		opts = append(opts, ForEachOutputRecover())
+	}
+
+	return opts
 }
 
 type MapOption func(*mapOptions) error
@@ -59,10 +105,81 @@ func MapBufferSize(bufferSize int) MapOption {
 	}
 }
 
+// MapPreserveOrder configures Map to emit the mapped items in the same order as the input items,
+// even with a pool size greater than 1.
+func MapPreserveOrder() MapOption {
+	return func(o *mapOptions) error {
+		o.preserveOrder = true
+		return nil
+	}
+}
+
+// MapMaxAhead sets how many items can be mapped ahead of the oldest item not emitted yet, when the order is preserved.
+// See ForEachOutputMaxAhead.
+func MapMaxAhead(maxAhead int) MapOption {
+	return func(o *mapOptions) error {
+		if maxAhead < 1 {
+			return fmt.Errorf("maxAhead must be greater than 0")
+		}
+		o.maxAhead = maxAhead
+		return nil
+	}
+}
+
+// MapRateLimit limits the rate at which the items are mapped to rate items per second, allowing bursts of up to burst items.
+// See ForEachOutputRateLimit.
+func MapRateLimit(rate float64, burst int) MapOption {
+	return func(o *mapOptions) error {
+		if err := validateRateLimit(rate, burst); err != nil {
+			return err
+		}
+		o.limiter = newTokenBucket(rate, burst)
+		return nil
+	}
+}
+
+// MapRetry configures Map to retry the function when it fails, before sending the error to the error channel.
+// See Retry for the available options.
+func MapRetry(opt ...RetryOption) MapOption {
+	return func(o *mapOptions) error {
+		retry, err := applyRetryOptions(opt)
+		if err != nil {
+			return err
+		}
+		o.retry = retry
+		return nil
+	}
+}
+
+// MapItemTimeout sets how long the function can take to map each item. See ForEachOutputItemTimeout.
+func MapItemTimeout(d time.Duration) MapOption {
+	return func(o *mapOptions) error {
+		if d <= 0 {
+			return fmt.Errorf("itemTimeout must be greater than 0")
+		}
+		o.itemTimeout = d
+		return nil
+	}
+}
+
+// MapRecover configures Map to recover from the panics of the function. See ForEachOutputRecover.
+func MapRecover() MapOption {
+	return func(o *mapOptions) error {
+		o.recoverPanics = true
+		return nil
+	}
+}
+
 func newDefaultMapOptions() *mapOptions {
 	return &mapOptions{
-		poolSize:   1,
-		bufferSize: 0,
+		poolSize:      1,
+		bufferSize:    0,
+		preserveOrder: false,
+		maxAhead:      0,
+		limiter:       nil,
+		retry:         nil,
+		itemTimeout:   0,
+		recoverPanics: false,
 	}
 }
 
@@ -0,0 +1,35 @@
+package rivo
+
+import "context"
+
+// MapItems returns a pipeline that applies a function to the value of each successful item from the input stream.
+// Failed items are forwarded unchanged. If the function returns an error, the item is emitted with an ItemError
+// wrapping it and carrying the original value, instead of sending the error to the error channel.
+// MapItems accepts the same options as Map. MapRetry retries the function, while its timeouts and recovered panics are
+// emitted in-band like its errors.
+func MapItems[T, U any](f func(context.Context, T) (U, error), opt ...MapOption) Pipeline[Item[T], Item[U]] {
+	o := mustMapOptions(opt)
+	call := itemCall{retry: o.retry, timeout: o.itemTimeout, recoverPanics: o.recoverPanics}
+
+	return Map(func(ctx context.Context, item Item[T]) (Item[U], error) {
+		if item.Err != nil {
+			return Item[U]{Err: item.Err}, nil
+		}
+
+		var v U
+		err := call.do(ctx, item.Val, func(ctx context.Context) error {
+			var err error
+			v, err = f(ctx, item.Val)
+			return err
+		})
+		if err != nil {
+			return Item[U]{Err: err}, nil
+		}
+
+		return Item[U]{Val: v}, nil
+	}, append(opt, func(o *mapOptions) error {
+		// The options are applied by the item call
+		o.retry, o.itemTimeout, o.recoverPanics = nil, 0, false
+		return nil
+	})...)
+}
@@ -0,0 +1,124 @@
+package rivo_test
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/agiac/rivo"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMapItems(t *testing.T) {
+	t.Run("map successful items", func(t *testing.T) {
+		ctx := context.Background()
+
+		upstreamErr := errors.New("upstream error")
+
+		g := Of(Item[string]{Val: "1"}, Item[string]{Err: upstreamErr}, Item[string]{Val: "a"}, Item[string]{Val: "3"})
+
+		toInt := MapItems(func(ctx context.Context, s string) (int, error) {
+			return strconv.Atoi(s)
+		})
+
+		got := Collect(Pipe(g, toInt)(ctx, nil, nil))
+
+		assert.Len(t, got, 4)
+		assert.Equal(t, Item[int]{Val: 1}, got[0])
+		assert.Equal(t, Item[int]{Err: upstreamErr}, got[1])
+		assert.Equal(t, Item[int]{Val: 3}, got[3])
+
+		var itemErr *ItemError
+		if assert.ErrorAs(t, got[2].Err, &itemErr) {
+			assert.Equal(t, "a", itemErr.Item)
+			assert.ErrorIs(t, got[2].Err, strconv.ErrSyntax)
+		}
+	})
+
+	t.Run("errors are not sent to the error channel", func(t *testing.T) {
+		ctx := context.Background()
+
+		fail := MapItems(func(ctx context.Context, n int) (int, error) {
+			return 0, errors.New("error")
+		})
+
+		got, err := RunCollect(ctx, Pipe3(Of(1, 2), ToItems[int](), fail))
+
+		assert.NoError(t, err)
+		assert.Len(t, got, 2)
+	})
+
+	t.Run("with options", func(t *testing.T) {
+		ctx := context.Background()
+
+		double := MapItems(func(ctx context.Context, n int) (int, error) {
+			return n * 2, nil
+		}, MapPoolSize(3), MapBufferSize(2))
+
+		out := Pipe3(Of(1, 2, 3), ToItems[int](), double)(ctx, nil, nil)
+
+		got := Collect(out)
+
+		assert.Equal(t, 2, cap(out))
+		assert.ElementsMatch(t, []Item[int]{{Val: 2}, {Val: 4}, {Val: 6}}, got)
+	})
+
+	t.Run("with retry", func(t *testing.T) {
+		ctx := context.Background()
+
+		var attempts atomic.Int32
+		flaky := MapItems(func(ctx context.Context, n int) (int, error) {
+			if attempts.Add(1) < 3 {
+				return 0, errors.New("flaky")
+			}
+			return n, nil
+		}, MapRetry(RetryMaxAttempts(3), RetryBackoff(time.Millisecond, time.Millisecond)))
+
+		upstreamErr := errors.New("upstream error")
+
+		got, err := RunCollect(ctx, Pipe(Of(Item[int]{Val: 1}, Item[int]{Err: upstreamErr}), flaky))
+
+		// The function is retried, while the failed items are forwarded once
+		assert.NoError(t, err)
+		assert.Equal(t, []Item[int]{{Val: 1}, {Err: upstreamErr}}, got)
+		assert.Equal(t, int32(3), attempts.Load())
+	})
+
+	t.Run("with item timeout and recover", func(t *testing.T) {
+		ctx := context.Background()
+
+		m := MapItems(func(ctx context.Context, n int) (int, error) {
+			switch n {
+			case 2:
+				<-ctx.Done()
+				return 0, ctx.Err()
+			case 3:
+				panic("three")
+			}
+			return n, nil
+		}, MapItemTimeout(10*time.Millisecond), MapRecover())
+
+		got, err := RunCollect(ctx, Pipe3(Of(1, 2, 3), ToItems[int](), m))
+
+		// The timeouts and the panics are emitted in-band, with the item
+		assert.NoError(t, err)
+		if assert.Len(t, got, 3) {
+			assert.Equal(t, Item[int]{Val: 1}, got[0])
+
+			var timeoutErr *TimeoutError
+			assert.ErrorAs(t, got[1].Err, &timeoutErr)
+
+			var panicErr *PanicError
+			assert.ErrorAs(t, got[2].Err, &panicErr)
+
+			var itemErr *ItemError
+			if assert.ErrorAs(t, got[2].Err, &itemErr) {
+				assert.Equal(t, 3, itemErr.Item)
+			}
+		}
+	})
+}
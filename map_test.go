@@ -3,7 +3,9 @@ package rivo_test
 import (
 	"context"
 	"fmt"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	. "github.com/agiac/rivo"
 
@@ -117,4 +119,93 @@ func TestMap(t *testing.T) {
 
 		assert.ElementsMatch(t, want, got)
 	})
+
+	t.Run("with preserve order", func(t *testing.T) {
+		ctx := context.Background()
+
+		mapFn := func(ctx context.Context, n int) (int, error) {
+			// Earlier items take longer, so that they finish after the later ones
+			time.Sleep(time.Duration(10-n) * time.Millisecond)
+			return n + 1, nil
+		}
+
+		in := Of(1, 2, 3, 4, 5, 6, 7, 8, 9)
+
+		m := Map(mapFn, MapPoolSize(4), MapPreserveOrder())
+
+		got := Collect(Pipe(in, m)(ctx, nil, nil))
+
+		want := []int{2, 3, 4, 5, 6, 7, 8, 9, 10}
+
+		assert.Equal(t, want, got)
+	})
+
+	t.Run("with max ahead", func(t *testing.T) {
+		ctx := context.Background()
+
+		var started atomic.Int32
+		release := make(chan struct{})
+
+		mapFn := func(ctx context.Context, n int) (int, error) {
+			started.Add(1)
+			if n == 1 {
+				<-release
+			}
+			return n, nil
+		}
+
+		in := Of(1, 2, 3, 4, 5, 6, 7, 8, 9, 10)
+
+		m := Map(mapFn, MapPoolSize(4), MapPreserveOrder(), MapMaxAhead(2))
+
+		out := Pipe(in, m)(ctx, nil, nil)
+
+		// While the first item is blocked, only a limited number of items can be started
+		time.Sleep(50 * time.Millisecond)
+		assert.LessOrEqual(t, started.Load(), int32(4))
+
+		close(release)
+
+		got := Collect(out)
+
+		assert.Equal(t, []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}, got)
+	})
+
+	t.Run("with item timeout", func(t *testing.T) {
+		ctx := context.Background()
+
+		m := Map(func(ctx context.Context, n int) (int, error) {
+			if n == 2 {
+				<-ctx.Done()
+				return 0, ctx.Err()
+			}
+			return n, nil
+		}, MapPoolSize(2), MapItemTimeout(10*time.Millisecond))
+
+		got, err := RunCollect(ctx, Pipe(Of(1, 2, 3), m))
+
+		// The error of the function is replaced by the TimeoutError
+		assert.ElementsMatch(t, []int{1, 3}, got)
+		assert.EqualError(t, err, "item timed out after 10ms")
+	})
+
+	t.Run("with item timeout and a slow consumer", func(t *testing.T) {
+		ctx := context.Background()
+
+		m := Map(func(ctx context.Context, n int) (int, error) {
+			return n, nil
+		}, MapItemTimeout(20*time.Millisecond))
+
+		var got []int
+		slow := Do(func(ctx context.Context, n int) error {
+			time.Sleep(50 * time.Millisecond)
+			got = append(got, n)
+			return nil
+		})
+
+		// The time spent waiting for the consumer doesn't count towards the timeout
+		err := Run(ctx, Pipe3(Of(1, 2, 3, 4, 5), m, slow))
+		assert.NoError(t, err)
+		assert.Equal(t, []int{1, 2, 3, 4, 5}, got)
+	})
 }
@@ -0,0 +1,237 @@
+package rivo
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+// DefaultLatencyBuckets are the default upper bounds of the buckets of the latency histograms of a MemoryObserver.
+var DefaultLatencyBuckets = []time.Duration{
+	10 * time.Microsecond,
+	50 * time.Microsecond,
+	100 * time.Microsecond,
+	500 * time.Microsecond,
+	time.Millisecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	5 * time.Second,
+	10 * time.Second,
+}
+
+// MemoryObserver is an Observer that keeps the metrics of the stages in memory, so that they can be inspected with
+// Snapshot. The metrics of the stages with the same name are aggregated, e.g. over multiple runs of a pipeline.
+// The stages that are not named are keyed by kind, followed by their ordinal among the stages of the same kind that are
+// not named if there's more than one, e.g. "Map" and "Map#2" for the two unnamed Map stages of Pipe3(Of(1), Map(f),
+// Map(g)).
+type MemoryObserver struct {
+	mu      sync.Mutex
+	buckets []time.Duration
+	stages  map[string]*memoryStageObserver
+}
+
+// NewMemoryObserver returns an empty MemoryObserver.
+// NewMemoryObserver panics if invalid options are provided.
+func NewMemoryObserver(opt ...MemoryObserverOption) *MemoryObserver {
+	o := mustMemoryObserverOptions(opt)
+
+	return &MemoryObserver{
+		buckets: o.buckets,
+		stages:  make(map[string]*memoryStageObserver),
+	}
+}
+
+// Stage implements Observer.
+func (m *MemoryObserver) Stage(name, kind string, n int) StageObserver {
+	key := name
+	if key == "" {
+		key = kind
+		if n > 1 {
+			key = fmt.Sprintf("%s#%d", kind, n)
+		}
+	}
+
+	return m.stage(key, name, kind)
+}
+
+func (m *MemoryObserver) stage(key, name, kind string) StageObserver {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s, ok := m.stages[key]
+	if !ok {
+		s = &memoryStageObserver{metrics: StageMetrics{
+			Name: name,
+			Kind: kind,
+			Latency: Histogram{
+				Bounds: m.buckets,
+				Counts: make([]int64, len(m.buckets)+1),
+			},
+		}}
+		m.stages[key] = s
+	}
+
+	return s
+}
+
+// Snapshot returns the current metrics of the stages, by name, or by kind and ordinal for the stages that are not named.
+func (m *MemoryObserver) Snapshot() map[string]StageMetrics {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	snapshot := make(map[string]StageMetrics, len(m.stages))
+	for key, s := range m.stages {
+		snapshot[key] = s.snapshot()
+	}
+
+	return snapshot
+}
+
+// StageMetrics are the metrics of a stage, as recorded by a MemoryObserver.
+type StageMetrics struct {
+	Name string
+	Kind string
+	// ItemsIn is the number of items received from the input stream.
+	ItemsIn int64
+	// ItemsOut is the number of items sent to the output stream.
+	ItemsOut int64
+	// Errors is the number of errors sent to the error channel.
+	Errors int64
+	// Latency is the histogram of the time taken to process each item.
+	Latency Histogram
+	// ReceiveBlocked is the total time spent waiting for the items of the input stream.
+	ReceiveBlocked time.Duration
+	// SendBlocked is the total time spent waiting for the downstream stage to receive the items of the output stream.
+	SendBlocked time.Duration
+	// BufferCapacity is the capacity of the buffer of the output stream.
+	BufferCapacity int
+	// BufferedSum is the sum of the number of items in the buffer of the output stream after each send, so that
+	// BufferedSum/ItemsOut is the average buffer occupancy.
+	BufferedSum int64
+	// MaxBuffered is the maximum number of items in the buffer of the output stream after a send.
+	MaxBuffered int
+}
+
+// Histogram is a histogram of durations.
+type Histogram struct {
+	// Bounds are the upper bounds of the buckets, in increasing order.
+	Bounds []time.Duration
+	// Counts are the number of observations in each bucket: Counts[i] is the number of observations greater than
+	// Bounds[i-1] and less than or equal to Bounds[i], while the last count is the number of observations greater
+	// than the last bound.
+	Counts []int64
+	// Count is the total number of observations.
+	Count int64
+	// Sum is the sum of the observations.
+	Sum time.Duration
+}
+
+func (h *Histogram) observe(d time.Duration) {
+	i, _ := slices.BinarySearch(h.Bounds, d)
+	h.Counts[i]++
+	h.Count++
+	h.Sum += d
+}
+
+type memoryStageObserver struct {
+	mu      sync.Mutex
+	metrics StageMetrics
+}
+
+func (s *memoryStageObserver) ItemReceived(blocked time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.metrics.ItemsIn++
+	s.metrics.ReceiveBlocked += blocked
+}
+
+func (s *memoryStageObserver) ItemSent(blocked time.Duration, buffered, capacity int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.metrics.ItemsOut++
+	s.metrics.SendBlocked += blocked
+	s.metrics.BufferCapacity = capacity
+	s.metrics.BufferedSum += int64(buffered)
+	s.metrics.MaxBuffered = max(s.metrics.MaxBuffered, buffered)
+}
+
+func (s *memoryStageObserver) ItemProcessed(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.metrics.Latency.observe(d)
+}
+
+func (s *memoryStageObserver) Error() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.metrics.Errors++
+}
+
+func (s *memoryStageObserver) snapshot() StageMetrics {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	m := s.metrics
+	m.Latency.Bounds = slices.Clone(m.Latency.Bounds)
+	m.Latency.Counts = slices.Clone(m.Latency.Counts)
+
+	return m
+}
+
+type memoryObserverOptions struct {
+	buckets []time.Duration
+}
+
+type MemoryObserverOption func(*memoryObserverOptions) error
+
+// MemoryObserverBuckets sets the upper bounds of the buckets of the latency histograms. They default to DefaultLatencyBuckets.
+func MemoryObserverBuckets(bounds ...time.Duration) MemoryObserverOption {
+	return func(o *memoryObserverOptions) error {
+		if len(bounds) == 0 {
+			return fmt.Errorf("buckets must not be empty")
+		}
+
+		for i := 1; i < len(bounds); i++ {
+			if bounds[i] <= bounds[i-1] {
+				return fmt.Errorf("buckets must be in increasing order")
+			}
+		}
+
+		o.buckets = slices.Clone(bounds)
+
+		return nil
+	}
+}
+
+func newDefaultMemoryObserverOptions() *memoryObserverOptions {
+	return &memoryObserverOptions{
+		buckets: slices.Clone(DefaultLatencyBuckets),
+	}
+}
+
+func applyMemoryObserverOptions(opts []MemoryObserverOption) (*memoryObserverOptions, error) {
+	o := newDefaultMemoryObserverOptions()
+	for _, opt := range opts {
+		if err := opt(o); err != nil {
+			return nil, err
+		}
+	}
+	return o, nil
+}
+
+func mustMemoryObserverOptions(opts []MemoryObserverOption) *memoryObserverOptions {
+	o, err := applyMemoryObserverOptions(opts)
+	if err != nil {
+		panic(fmt.Sprintf("invalid MemoryObserverOption: %v", err))
+	}
+	return o
+}
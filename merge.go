@@ -3,6 +3,7 @@ package rivo
 import (
 	"context"
 	"sync"
+	"time"
 )
 
 // Merge merges multiple input channels into a single output channel.
@@ -10,16 +11,39 @@ import (
 func Merge[T any](ctx context.Context, channels ...<-chan T) <-chan T {
 	out := make(chan T)
 
+	in := make([]any, len(channels))
+	for i, ch := range channels {
+		in[i] = Stream[T](ch)
+	}
+
+	if describeStage(ctx, TopologyNode{Kind: "Merge"}, in, []any{Stream[T](out)}) {
+		go func() {
+			defer close(out)
+			for _, ch := range channels {
+				drain(ch)
+			}
+		}()
+		return out
+	}
+
+	obs := ObserveStage(ctx, "Merge")
+
 	go func() {
+		labelGoroutine(ctx, "Merge", -1)
+
 		defer close(out)
 
 		wg := sync.WaitGroup{}
 
-		for _, ch := range channels {
+		for i, ch := range channels {
 			wg.Add(1)
 			go func(c <-chan T) {
+				labelGoroutine(ctx, "Merge", i)
+
 				defer wg.Done()
 				for {
+					start := time.Now()
+
 					select {
 					case <-ctx.Done():
 						return
@@ -27,10 +51,11 @@ func Merge[T any](ctx context.Context, channels ...<-chan T) <-chan T {
 						if !ok {
 							return
 						}
-						select {
-						case <-ctx.Done():
+
+						obs.ItemReceived(time.Since(start))
+
+						if !observedSend(ctx, obs, out, item) {
 							return
-						case out <- item:
 						}
 					}
 				}
@@ -0,0 +1,248 @@
+package metrics
+
+import (
+	"bufio"
+	"expvar"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/agiac/rivo"
+)
+
+// Registry collects the metrics of the stages of named pipelines and exports them through expvar and in the
+// Prometheus text format. Each pipeline reports to its own rivo.MemoryObserver, see Observer.
+type Registry struct {
+	mu        sync.Mutex
+	opt       []rivo.MemoryObserverOption
+	pipelines map[string]*rivo.MemoryObserver
+}
+
+// NewRegistry returns an empty Registry. The options configure the observers of the pipelines.
+// NewRegistry panics if invalid options are provided.
+func NewRegistry(opt ...rivo.MemoryObserverOption) *Registry {
+	// Validate the options once, rather than when the first pipeline is observed
+	rivo.NewMemoryObserver(opt...)
+
+	return &Registry{
+		opt:       opt,
+		pipelines: make(map[string]*rivo.MemoryObserver),
+	}
+}
+
+// Observer returns the observer of the pipeline with the given name, creating it if needed. Use it with rivo.WithObserver.
+func (r *Registry) Observer(pipeline string) *rivo.MemoryObserver {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	obs, ok := r.pipelines[pipeline]
+	if !ok {
+		obs = rivo.NewMemoryObserver(r.opt...)
+		r.pipelines[pipeline] = obs
+	}
+
+	return obs
+}
+
+// Instrument returns a pipeline that runs p reporting the metrics of its stages to the registry under the given
+// pipeline name. See rivo.WithObserver.
+func Instrument[T, U any](r *Registry, pipeline string, p rivo.Pipeline[T, U]) rivo.Pipeline[T, U] {
+	return rivo.WithObserver(r.Observer(pipeline), p)
+}
+
+// stage is the snapshot of the metrics of a stage of a pipeline.
+type stage struct {
+	pipeline string
+	stage    string
+	metrics  rivo.StageMetrics
+}
+
+// snapshot returns the metrics of all the stages, sorted by pipeline and stage.
+func (r *Registry) snapshot() []stage {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var stages []stage
+
+	for pipeline, obs := range r.pipelines {
+		for name, m := range obs.Snapshot() {
+			stages = append(stages, stage{pipeline: pipeline, stage: name, metrics: m})
+		}
+	}
+
+	slices.SortFunc(stages, func(a, b stage) int {
+		if c := strings.Compare(a.pipeline, b.pipeline); c != 0 {
+			return c
+		}
+		return strings.Compare(a.stage, b.stage)
+	})
+
+	return stages
+}
+
+// Handler returns an http.Handler that serves the metrics in the Prometheus text format. See WritePrometheus.
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		_ = r.WritePrometheus(w)
+	})
+}
+
+// WritePrometheus writes the metrics in the Prometheus text format. Each metric is labeled with the name of the
+// pipeline, the name of the stage, or its key in rivo.MemoryObserver if it's not named, e.g. "Map#2", and the kind
+// of the stage:
+//
+//	rivo_stage_items_in_total                 counter    items received from the input stream
+//	rivo_stage_items_out_total                counter    items sent to the output stream
+//	rivo_stage_errors_total                   counter    errors sent to the error channel
+//	rivo_stage_latency_seconds                histogram  time taken to process each item
+//	rivo_stage_receive_blocked_seconds_total  counter    time spent waiting for the input stream
+//	rivo_stage_send_blocked_seconds_total     counter    time spent waiting for the downstream stage
+//	rivo_stage_buffer_capacity                gauge      capacity of the buffer of the output stream
+//	rivo_stage_buffer_occupancy_max           gauge      maximum number of items in the buffer after a send
+//	rivo_stage_buffer_occupancy_sum           counter    sum of the number of items in the buffer after each send
+func (r *Registry) WritePrometheus(w io.Writer) error {
+	stages := r.snapshot()
+
+	bw := bufio.NewWriter(w)
+
+	family := func(name, typ, help string, value func(m rivo.StageMetrics) float64) {
+		fmt.Fprintf(bw, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+		for _, s := range stages {
+			fmt.Fprintf(bw, "%s{%s} %s\n", name, labels(s), formatFloat(value(s.metrics)))
+		}
+	}
+
+	family("rivo_stage_items_in_total", "counter", "Items received from the input stream.", func(m rivo.StageMetrics) float64 {
+		return float64(m.ItemsIn)
+	})
+
+	family("rivo_stage_items_out_total", "counter", "Items sent to the output stream.", func(m rivo.StageMetrics) float64 {
+		return float64(m.ItemsOut)
+	})
+
+	family("rivo_stage_errors_total", "counter", "Errors sent to the error channel.", func(m rivo.StageMetrics) float64 {
+		return float64(m.Errors)
+	})
+
+	const latency = "rivo_stage_latency_seconds"
+
+	fmt.Fprintf(bw, "# HELP %s Time taken to process each item.\n# TYPE %s histogram\n", latency, latency)
+	for _, s := range stages {
+		h := s.metrics.Latency
+
+		var cumulative int64
+		for i, bound := range h.Bounds {
+			cumulative += h.Counts[i]
+			fmt.Fprintf(bw, "%s_bucket{%s,le=\"%s\"} %d\n", latency, labels(s), formatFloat(bound.Seconds()), cumulative)
+		}
+
+		fmt.Fprintf(bw, "%s_bucket{%s,le=\"+Inf\"} %d\n", latency, labels(s), h.Count)
+		fmt.Fprintf(bw, "%s_sum{%s} %s\n", latency, labels(s), formatFloat(h.Sum.Seconds()))
+		fmt.Fprintf(bw, "%s_count{%s} %d\n", latency, labels(s), h.Count)
+	}
+
+	family("rivo_stage_receive_blocked_seconds_total", "counter", "Time spent waiting for the items of the input stream.", func(m rivo.StageMetrics) float64 {
+		return m.ReceiveBlocked.Seconds()
+	})
+
+	family("rivo_stage_send_blocked_seconds_total", "counter", "Time spent waiting for the downstream stage to receive the items of the output stream.", func(m rivo.StageMetrics) float64 {
+		return m.SendBlocked.Seconds()
+	})
+
+	family("rivo_stage_buffer_capacity", "gauge", "Capacity of the buffer of the output stream.", func(m rivo.StageMetrics) float64 {
+		return float64(m.BufferCapacity)
+	})
+
+	family("rivo_stage_buffer_occupancy_max", "gauge", "Maximum number of items in the buffer of the output stream after a send.", func(m rivo.StageMetrics) float64 {
+		return float64(m.MaxBuffered)
+	})
+
+	family("rivo_stage_buffer_occupancy_sum", "counter", "Sum of the number of items in the buffer of the output stream after each send.", func(m rivo.StageMetrics) float64 {
+		return float64(m.BufferedSum)
+	})
+
+	return bw.Flush()
+}
+
+func labels(s stage) string {
+	return fmt.Sprintf(`pipeline="%s",stage="%s",kind="%s"`, escapeLabel(s.pipeline), escapeLabel(s.stage), escapeLabel(s.metrics.Kind))
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabel(v string) string {
+	return labelEscaper.Replace(v)
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// Publish publishes the metrics through expvar under the given name, as a JSON object with the metrics of each stage
+// by pipeline and stage name. Like expvar.Publish, it panics if the name is already in use.
+func (r *Registry) Publish(name string) {
+	expvar.Publish(name, expvar.Func(r.expvarValue))
+}
+
+type expvarStage struct {
+	Kind                  string          `json:"kind"`
+	ItemsIn               int64           `json:"items_in"`
+	ItemsOut              int64           `json:"items_out"`
+	Errors                int64           `json:"errors"`
+	Latency               expvarHistogram `json:"latency"`
+	ReceiveBlockedSeconds float64         `json:"receive_blocked_seconds"`
+	SendBlockedSeconds    float64         `json:"send_blocked_seconds"`
+	BufferCapacity        int             `json:"buffer_capacity"`
+	BufferOccupancyMax    int             `json:"buffer_occupancy_max"`
+	BufferOccupancySum    int64           `json:"buffer_occupancy_sum"`
+}
+
+type expvarHistogram struct {
+	Count      int64            `json:"count"`
+	SumSeconds float64          `json:"sum_seconds"`
+	Buckets    map[string]int64 `json:"buckets"`
+}
+
+func (r *Registry) expvarValue() any {
+	value := make(map[string]map[string]expvarStage)
+
+	for _, s := range r.snapshot() {
+		if value[s.pipeline] == nil {
+			value[s.pipeline] = make(map[string]expvarStage)
+		}
+
+		m := s.metrics
+
+		buckets := make(map[string]int64, len(m.Latency.Bounds)+1)
+		var cumulative int64
+		for i, bound := range m.Latency.Bounds {
+			cumulative += m.Latency.Counts[i]
+			buckets[formatFloat(bound.Seconds())] = cumulative
+		}
+		buckets["+Inf"] = m.Latency.Count
+
+		value[s.pipeline][s.stage] = expvarStage{
+			Kind:     m.Kind,
+			ItemsIn:  m.ItemsIn,
+			ItemsOut: m.ItemsOut,
+			Errors:   m.Errors,
+			Latency: expvarHistogram{
+				Count:      m.Latency.Count,
+				SumSeconds: m.Latency.Sum.Seconds(),
+				Buckets:    buckets,
+			},
+			ReceiveBlockedSeconds: m.ReceiveBlocked.Seconds(),
+			SendBlockedSeconds:    m.SendBlocked.Seconds(),
+			BufferCapacity:        m.BufferCapacity,
+			BufferOccupancyMax:    m.MaxBuffered,
+			BufferOccupancySum:    m.BufferedSum,
+		}
+	}
+
+	return value
+}
@@ -0,0 +1,168 @@
+package metrics_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"expvar"
+	"fmt"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/agiac/rivo"
+	. "github.com/agiac/rivo/metrics"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func ExampleRegistry() {
+	ctx := context.Background()
+
+	registry := NewRegistry(rivo.MemoryObserverBuckets(time.Second))
+
+	double := rivo.Named("double", rivo.Map(func(ctx context.Context, n int) (int, error) {
+		return n * 2, nil
+	}))
+
+	p := Instrument(registry, "numbers", rivo.Pipe(rivo.Of(1, 2, 3), double))
+
+	_, _ = rivo.RunCollect(ctx, p)
+
+	// Serve the metrics with http.Handle("/metrics", registry.Handler())
+	var b strings.Builder
+	_ = registry.WritePrometheus(&b)
+
+	for _, line := range strings.Split(b.String(), "\n") {
+		if strings.HasPrefix(line, "rivo_stage_items_") || strings.HasPrefix(line, "rivo_stage_latency_seconds_bucket") {
+			fmt.Println(line)
+		}
+	}
+
+	// Output:
+	// rivo_stage_items_in_total{pipeline="numbers",stage="double",kind="Map"} 3
+	// rivo_stage_items_out_total{pipeline="numbers",stage="double",kind="Map"} 3
+	// rivo_stage_latency_seconds_bucket{pipeline="numbers",stage="double",kind="Map",le="1"} 3
+	// rivo_stage_latency_seconds_bucket{pipeline="numbers",stage="double",kind="Map",le="+Inf"} 3
+}
+
+func TestRegistry(t *testing.T) {
+	errOdd := errors.New("odd")
+
+	run := func(registry *Registry) {
+		ctx := context.Background()
+
+		even := rivo.Filter(func(ctx context.Context, n int) (bool, error) {
+			if n == 3 {
+				return false, errOdd
+			}
+			return n%2 == 0, nil
+		}, rivo.FilterBufferSize(2))
+
+		double := rivo.Named("double \"x2\"", rivo.Map(func(ctx context.Context, n int) (int, error) {
+			return n * 2, nil
+		}))
+
+		_, _ = rivo.RunCollect(ctx, Instrument(registry, "a", rivo.Pipe(rivo.Of(1, 2, 3, 4), even)))
+		_, _ = rivo.RunCollect(ctx, Instrument(registry, "b", rivo.Pipe(rivo.Of(1, 2), double)))
+	}
+
+	t.Run("prometheus handler", func(t *testing.T) {
+		registry := NewRegistry(rivo.MemoryObserverBuckets(time.Millisecond, time.Hour))
+
+		run(registry)
+
+		rec := httptest.NewRecorder()
+		registry.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+
+		assert.Equal(t, "text/plain; version=0.0.4; charset=utf-8", rec.Header().Get("Content-Type"))
+
+		body, err := io.ReadAll(rec.Body)
+		assert.NoError(t, err)
+
+		lines := strings.Split(string(body), "\n")
+
+		for _, want := range []string{
+			`# HELP rivo_stage_items_in_total Items received from the input stream.`,
+			`# TYPE rivo_stage_items_in_total counter`,
+			`rivo_stage_items_in_total{pipeline="a",stage="Filter",kind="Filter"} 4`,
+			`rivo_stage_items_in_total{pipeline="b",stage="double \"x2\"",kind="Map"} 2`,
+			`rivo_stage_items_out_total{pipeline="a",stage="Filter",kind="Filter"} 2`,
+			`rivo_stage_errors_total{pipeline="a",stage="Filter",kind="Filter"} 1`,
+			`rivo_stage_errors_total{pipeline="b",stage="double \"x2\"",kind="Map"} 0`,
+			`# TYPE rivo_stage_latency_seconds histogram`,
+			`rivo_stage_latency_seconds_bucket{pipeline="a",stage="Filter",kind="Filter",le="3600"} 4`,
+			`rivo_stage_latency_seconds_bucket{pipeline="a",stage="Filter",kind="Filter",le="+Inf"} 4`,
+			`rivo_stage_latency_seconds_count{pipeline="a",stage="Filter",kind="Filter"} 4`,
+			`# TYPE rivo_stage_send_blocked_seconds_total counter`,
+			`# TYPE rivo_stage_receive_blocked_seconds_total counter`,
+			`rivo_stage_buffer_capacity{pipeline="a",stage="Filter",kind="Filter"} 2`,
+			`# TYPE rivo_stage_buffer_occupancy_max gauge`,
+			`# TYPE rivo_stage_buffer_occupancy_sum counter`,
+		} {
+			assert.Contains(t, lines, want)
+		}
+
+		// The stages are sorted by pipeline and stage
+		assert.Less(t,
+			strings.Index(string(body), `rivo_stage_items_in_total{pipeline="a"`),
+			strings.Index(string(body), `rivo_stage_items_in_total{pipeline="b"`),
+		)
+	})
+
+	t.Run("expvar", func(t *testing.T) {
+		// expvar names are global, and tests can run more than once in the same process
+		name := fmt.Sprintf("rivo_pipelines_%d", time.Now().UnixNano())
+
+		registry := NewRegistry(rivo.MemoryObserverBuckets(time.Hour))
+		registry.Publish(name)
+
+		run(registry)
+
+		var got map[string]map[string]struct {
+			Kind     string `json:"kind"`
+			ItemsIn  int64  `json:"items_in"`
+			ItemsOut int64  `json:"items_out"`
+			Errors   int64  `json:"errors"`
+			Latency  struct {
+				Count   int64            `json:"count"`
+				Buckets map[string]int64 `json:"buckets"`
+			} `json:"latency"`
+			BufferCapacity int `json:"buffer_capacity"`
+		}
+
+		assert.NoError(t, json.Unmarshal([]byte(expvar.Get(name).String()), &got))
+
+		filter := got["a"]["Filter"]
+		assert.Equal(t, "Filter", filter.Kind)
+		assert.Equal(t, int64(4), filter.ItemsIn)
+		assert.Equal(t, int64(2), filter.ItemsOut)
+		assert.Equal(t, int64(1), filter.Errors)
+		assert.Equal(t, int64(4), filter.Latency.Count)
+		assert.Equal(t, map[string]int64{"3600": 4, "+Inf": 4}, filter.Latency.Buckets)
+		assert.Equal(t, 2, filter.BufferCapacity)
+
+		double := got["b"]["double \"x2\""]
+		assert.Equal(t, "Map", double.Kind)
+		assert.Equal(t, int64(2), double.ItemsOut)
+
+		assert.Panics(t, func() {
+			registry.Publish(name)
+		})
+	})
+
+	t.Run("same observer for the same pipeline", func(t *testing.T) {
+		registry := NewRegistry()
+
+		assert.Same(t, registry.Observer("a"), registry.Observer("a"))
+		assert.NotSame(t, registry.Observer("a"), registry.Observer("b"))
+	})
+
+	t.Run("invalid options", func(t *testing.T) {
+		assert.Panics(t, func() {
+			NewRegistry(rivo.MemoryObserverBuckets())
+		})
+	})
+}
@@ -0,0 +1,123 @@
+package rivo
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Observer receives the metrics of the stages of a pipeline. See WithObserver.
+type Observer interface {
+	// Stage returns the StageObserver for a run of the stage with the given name, set with Named, if any, and kind,
+	// e.g. "Map" or "Batch". n is the ordinal of the stage among the stages of the run of the pipeline with the same
+	// name and kind, starting from 1, so that the stages that are not named can be told apart.
+	Stage(name, kind string, n int) StageObserver
+}
+
+// StageObserver receives the metrics of a run of a stage. Its methods are called concurrently by the workers of the stage.
+type StageObserver interface {
+	// ItemReceived is called for each item received from the input stream, with the time spent waiting for it.
+	ItemReceived(blocked time.Duration)
+	// ItemSent is called for each item sent to the output stream, with the time spent waiting for the downstream stage
+	// to receive it and with the number of items in the buffer of the output stream right after, out of its capacity.
+	ItemSent(blocked time.Duration, buffered, capacity int)
+	// ItemProcessed is called for each item processed by the stage, with the time it took, e.g. to map it.
+	ItemProcessed(d time.Duration)
+	// Error is called for each error sent to the error channel by the stage.
+	Error()
+}
+
+type observerKey struct{}
+
+// observedPipeline is the observer of a run of a pipeline, which numbers its stages by name and kind, in the order in
+// which they are started.
+type observedPipeline struct {
+	obs    Observer
+	mu     sync.Mutex
+	stages map[observedStage]int
+}
+
+type observedStage struct {
+	name, kind string
+}
+
+func (p *observedPipeline) stage(name, kind string) StageObserver {
+	p.mu.Lock()
+	key := observedStage{name, kind}
+	p.stages[key]++
+	n := p.stages[key]
+	p.mu.Unlock()
+
+	return p.obs.Stage(name, kind, n)
+}
+
+// WithObserver returns a pipeline that runs p reporting the metrics of its stages to the given observer.
+// The stages based on ForEachOutput, such as Map, Filter or Do, and FromFunc, Batch, Merge and the IO adapters report
+// the items they receive and send, with the time spent waiting on the input and output streams, the time taken to
+// process each item and their errors. The stages should be named with Named, so that they can be told apart: the
+// stages that are not named are told apart by kind and by the order in which they are started, which is stable across
+// runs as long as the pipeline starts its stages in the same order, as Pipe does.
+// Sending the output of an observed ForEachOutput stage goes through an extra goroutine, so that the time spent
+// waiting for the downstream stage can be measured.
+func WithObserver[T, U any](obs Observer, p Pipeline[T, U]) Pipeline[T, U] {
+	return func(ctx context.Context, in Stream[T], errs chan<- error) Stream[U] {
+		return p(context.WithValue(ctx, observerKey{}, &observedPipeline{obs: obs, stages: make(map[observedStage]int)}), in, errs)
+	}
+}
+
+// ObserveStage returns the StageObserver for a run of a stage of the given kind with the given context, so that custom
+// stages can report their metrics to the observer set with WithObserver. If there's no observer, the returned
+// StageObserver discards the metrics.
+func ObserveStage(ctx context.Context, kind string) StageObserver {
+	if obs := observeStage(ctx, kind); obs != nil {
+		return obs
+	}
+	return noopStageObserver{}
+}
+
+// observeStage returns the StageObserver for a run of a stage of the given kind, or nil if there's no observer.
+func observeStage(ctx context.Context, kind string) StageObserver {
+	p, ok := ctx.Value(observerKey{}).(*observedPipeline)
+	if !ok {
+		return nil
+	}
+	return p.stage(stageName(ctx), kind)
+}
+
+type noopStageObserver struct{}
+
+func (noopStageObserver) ItemReceived(time.Duration)       {}
+func (noopStageObserver) ItemSent(time.Duration, int, int) {}
+func (noopStageObserver) ItemProcessed(time.Duration)      {}
+func (noopStageObserver) Error()                           {}
+
+// observedSend sends an item to the output stream, reporting it to the StageObserver, and returns false if the context
+// is done first.
+func observedSend[T any](ctx context.Context, obs StageObserver, out chan<- T, v T) bool {
+	start := time.Now()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case out <- v:
+		obs.ItemSent(time.Since(start), len(out), cap(out))
+		return true
+	}
+}
+
+// observedForward sends the items of the stream in to out, reporting them to the StageObserver, until in is closed.
+// The returned channel is closed once done. Once the context is done, the items are discarded, so that the stage
+// writing to in is not blocked.
+func observedForward[T any](ctx context.Context, obs StageObserver, in <-chan T, out chan<- T) <-chan struct{} {
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		for v := range in {
+			observedSend(ctx, obs, out, v)
+		}
+	}()
+
+	return done
+}
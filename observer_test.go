@@ -0,0 +1,296 @@
+package rivo_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/agiac/rivo"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func ExampleWithObserver() {
+	ctx := context.Background()
+
+	obs := NewMemoryObserver()
+
+	double := Named("double", Map(func(ctx context.Context, n int) (int, error) {
+		if n == 3 {
+			return 0, errors.New("three")
+		}
+		return n * 2, nil
+	}))
+
+	_, _ = RunCollect(ctx, WithObserver(obs, Pipe(Of(1, 2, 3, 4), double)))
+
+	m := obs.Snapshot()["double"]
+
+	fmt.Println("in:", m.ItemsIn, "out:", m.ItemsOut, "errors:", m.Errors, "processed:", m.Latency.Count)
+
+	// Output:
+	// in: 4 out: 3 errors: 1 processed: 4
+}
+
+// stagesObserver records the stages it's given, discarding their metrics.
+type stagesObserver struct {
+	mu     sync.Mutex
+	stages []string
+}
+
+func (o *stagesObserver) Stage(name, kind string, n int) StageObserver {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if name != "" {
+		kind = fmt.Sprintf("%s (%s)", name, kind)
+	}
+	o.stages = append(o.stages, fmt.Sprintf("%s#%d", kind, n))
+
+	return ObserveStage(context.Background(), kind)
+}
+
+func TestWithObserver(t *testing.T) {
+	errOdd := errors.New("odd")
+
+	t.Run("ForEachOutput based stages", func(t *testing.T) {
+		ctx := context.Background()
+
+		obs := NewMemoryObserver(MemoryObserverBuckets(time.Millisecond, time.Hour))
+
+		slow := Named("slow", Map(func(ctx context.Context, n int) (int, error) {
+			time.Sleep(2 * time.Millisecond)
+			return n, nil
+		}, MapPoolSize(2)))
+
+		even := Filter(func(ctx context.Context, n int) (bool, error) {
+			if n == 5 {
+				return false, errOdd
+			}
+			return n%2 == 0, nil
+		}, FilterPoolSize(3), FilterBufferSize(2))
+
+		got, err := RunCollect(ctx, WithObserver(obs, Pipe3(Of(1, 2, 3, 4, 5, 6), slow, even)))
+
+		assert.ElementsMatch(t, []int{2, 4, 6}, got)
+		assert.ErrorIs(t, err, errOdd)
+
+		snapshot := obs.Snapshot()
+		assert.Len(t, snapshot, 2)
+
+		s := snapshot["slow"]
+		assert.Equal(t, "slow", s.Name)
+		assert.Equal(t, "Map", s.Kind)
+		assert.Equal(t, int64(6), s.ItemsIn)
+		assert.Equal(t, int64(6), s.ItemsOut)
+		assert.Equal(t, int64(0), s.Errors)
+		assert.Equal(t, []time.Duration{time.Millisecond, time.Hour}, s.Latency.Bounds)
+		assert.Equal(t, []int64{0, 6, 0}, s.Latency.Counts)
+		assert.Equal(t, int64(6), s.Latency.Count)
+		assert.GreaterOrEqual(t, s.Latency.Sum, 12*time.Millisecond)
+
+		f := snapshot["Filter"]
+		assert.Equal(t, "", f.Name)
+		assert.Equal(t, "Filter", f.Kind)
+		assert.Equal(t, int64(6), f.ItemsIn)
+		assert.Equal(t, int64(3), f.ItemsOut)
+		assert.Equal(t, int64(1), f.Errors)
+		assert.Equal(t, int64(6), f.Latency.Count)
+		assert.Equal(t, 2, f.BufferCapacity)
+		// The filter waits for the slow stage
+		assert.Greater(t, f.ReceiveBlocked, time.Millisecond)
+	})
+
+	t.Run("unnamed stages of the same kind", func(t *testing.T) {
+		ctx := context.Background()
+
+		obs := NewMemoryObserver()
+
+		double := Map(func(ctx context.Context, n int) (int, error) {
+			return n * 2, nil
+		})
+
+		even := Filter(func(ctx context.Context, n int) (bool, error) {
+			return n%4 == 0, nil
+		})
+
+		p := WithObserver(obs, Pipe4(Of(1, 2, 3), double, even, double))
+
+		for run := 0; run < 2; run++ {
+			got, err := RunCollect(ctx, p)
+			assert.NoError(t, err)
+			assert.Equal(t, []int{8}, got)
+		}
+
+		snapshot := obs.Snapshot()
+		assert.Len(t, snapshot, 3)
+
+		// The metrics of each stage are aggregated over the runs
+		assert.Equal(t, int64(6), snapshot["Map"].ItemsIn)
+		assert.Equal(t, int64(6), snapshot["Map"].ItemsOut)
+		assert.Equal(t, int64(2), snapshot["Map#2"].ItemsIn)
+		assert.Equal(t, "Map", snapshot["Map#2"].Kind)
+		assert.Empty(t, snapshot["Map#2"].Name)
+		assert.Equal(t, int64(2), snapshot["Filter"].ItemsOut)
+	})
+
+	t.Run("stage ordinals with a custom observer", func(t *testing.T) {
+		ctx := context.Background()
+
+		obs := &stagesObserver{}
+
+		double := Map(func(ctx context.Context, n int) (int, error) {
+			return n * 2, nil
+		})
+
+		p := WithObserver(obs, Pipe4(Of(1), double, Named("double", double), double))
+
+		for run := 0; run < 2; run++ {
+			_, err := RunCollect(ctx, p)
+			assert.NoError(t, err)
+		}
+
+		// The stages are numbered by name and kind within each run
+		want := []string{"Map#1", "double (Map)#1", "Map#2"}
+		assert.Equal(t, append(want, want...), obs.stages)
+	})
+
+	t.Run("preserve order", func(t *testing.T) {
+		ctx := context.Background()
+
+		obs := NewMemoryObserver()
+
+		double := Map(func(ctx context.Context, n int) (int, error) {
+			return n * 2, nil
+		}, MapPoolSize(3), MapPreserveOrder())
+
+		got, err := RunCollect(ctx, WithObserver(obs, Pipe(Of(1, 2, 3, 4), double)))
+
+		assert.NoError(t, err)
+		assert.Equal(t, []int{2, 4, 6, 8}, got)
+
+		m := obs.Snapshot()["Map"]
+		assert.Equal(t, int64(4), m.ItemsIn)
+		assert.Equal(t, int64(4), m.ItemsOut)
+		assert.Equal(t, int64(4), m.Latency.Count)
+	})
+
+	t.Run("time blocked on send and buffer occupancy", func(t *testing.T) {
+		ctx := context.Background()
+
+		obs := NewMemoryObserver()
+
+		double := Map(func(ctx context.Context, n int) (int, error) {
+			return n * 2, nil
+		}, MapBufferSize(3))
+
+		out := WithObserver(obs, Pipe(Of(1, 2, 3, 4, 5), double))(ctx, nil, nil)
+
+		// Let the buffer fill up before consuming
+		time.Sleep(20 * time.Millisecond)
+
+		assert.Equal(t, []int{2, 4, 6, 8, 10}, Collect(out))
+
+		m := obs.Snapshot()["Map"]
+		assert.Equal(t, int64(5), m.ItemsOut)
+		assert.Equal(t, 3, m.BufferCapacity)
+		assert.Equal(t, 3, m.MaxBuffered)
+		assert.Greater(t, m.BufferedSum, int64(3))
+		assert.Greater(t, m.SendBlocked, 10*time.Millisecond)
+	})
+
+	t.Run("FromFunc, Batch and Merge", func(t *testing.T) {
+		ctx := context.Background()
+
+		obs := NewMemoryObserver()
+
+		count := 0
+		gen := FromFunc(func(ctx context.Context) (int, bool, error) {
+			count++
+			if count == 2 {
+				return 0, true, errOdd
+			}
+			return count, count <= 5, nil
+		}, FromFuncBufferSize(1))
+
+		merge := func(ctx context.Context, in Stream[int], errs chan<- error) Stream[int] {
+			return Merge(ctx, in, Of(10, 20)(ctx, nil, errs))
+		}
+
+		got, err := RunCollect(ctx, WithObserver(obs, Pipe3(gen, merge, Batch[int](10))))
+
+		assert.ErrorIs(t, err, errOdd)
+		assert.Len(t, got, 1)
+		assert.ElementsMatch(t, []int{1, 3, 4, 5, 10, 20}, got[0])
+
+		snapshot := obs.Snapshot()
+
+		f := snapshot["FromFunc"]
+		assert.Equal(t, int64(4), f.ItemsOut)
+		assert.Equal(t, int64(1), f.Errors)
+		assert.Equal(t, int64(4), f.Latency.Count)
+		assert.Equal(t, 1, f.BufferCapacity)
+
+		m := snapshot["Merge"]
+		assert.Equal(t, int64(6), m.ItemsIn)
+		assert.Equal(t, int64(6), m.ItemsOut)
+
+		b := snapshot["Batch"]
+		assert.Equal(t, int64(6), b.ItemsIn)
+		assert.Equal(t, int64(1), b.ItemsOut)
+	})
+
+	t.Run("aggregate runs", func(t *testing.T) {
+		ctx := context.Background()
+
+		obs := NewMemoryObserver()
+
+		p := WithObserver(obs, Pipe(Of(1, 2, 3), Named("id", Map(func(ctx context.Context, n int) (int, error) {
+			return n, nil
+		}))))
+
+		_, _ = RunCollect(ctx, p)
+		_, _ = RunCollect(ctx, p)
+
+		assert.Equal(t, int64(6), obs.Snapshot()["id"].ItemsOut)
+	})
+
+	t.Run("custom stages", func(t *testing.T) {
+		ctx := context.Background()
+
+		obs := NewMemoryObserver()
+
+		custom := func(ctx context.Context, in Stream[None], errs chan<- error) Stream[None] {
+			s := ObserveStage(ctx, "Custom")
+			s.ItemReceived(time.Second)
+			s.Error()
+
+			out := make(chan None)
+			close(out)
+			return out
+		}
+
+		assert.NoError(t, Run(ctx, WithObserver(obs, custom)))
+
+		m := obs.Snapshot()["Custom"]
+		assert.Equal(t, int64(1), m.ItemsIn)
+		assert.Equal(t, int64(1), m.Errors)
+		assert.Equal(t, time.Second, m.ReceiveBlocked)
+
+		// Without an observer, the metrics are discarded
+		assert.NoError(t, Run(ctx, custom))
+	})
+
+	t.Run("invalid options", func(t *testing.T) {
+		assert.Panics(t, func() {
+			NewMemoryObserver(MemoryObserverBuckets())
+		})
+
+		assert.Panics(t, func() {
+			NewMemoryObserver(MemoryObserverBuckets(time.Second, time.Millisecond))
+		})
+	})
+}
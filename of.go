@@ -7,6 +7,11 @@ func Of[T any](items ...T) Generator[T] {
 	return func(ctx context.Context, _ Stream[None], _ chan<- error) Stream[T] {
 		out := make(chan T)
 
+		if describeStage(ctx, TopologyNode{Kind: "Of"}, nil, []any{Stream[T](out)}) {
+			close(out)
+			return out
+		}
+
 		go func() {
 			defer close(out)
 
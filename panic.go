@@ -0,0 +1,32 @@
+package rivo
+
+import (
+	"fmt"
+	"runtime/debug"
+)
+
+// PanicError is the error sent to the error channel when a function passed to a pipeline panics and the pipeline
+// is configured to recover from panics, e.g. with MapRecover. Since it's sent as any other error, the pipeline
+// goes on with the next items or is stopped according to its ErrorPolicy.
+type PanicError struct {
+	// Value is the value passed to panic.
+	Value any
+	// Stack is the stack trace of the goroutine that panicked.
+	Stack []byte
+	// Item is the item that was being processed, if any.
+	Item any
+}
+
+func newPanicError(value, item any) *PanicError {
+	return &PanicError{Value: value, Stack: debug.Stack(), Item: item}
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
+// Unwrap returns the value passed to panic if it's an error.
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
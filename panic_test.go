@@ -0,0 +1,191 @@
+package rivo_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+
+	. "github.com/agiac/rivo"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func ExamplePanicError() {
+	ctx := context.Background()
+
+	parse := Map(func(ctx context.Context, s string) (int, error) {
+		if s == "" {
+			panic("empty string")
+		}
+		return len(s), nil
+	}, MapRecover())
+
+	got, err := RunCollect(ctx, Pipe(Of("a", "", "abc"), parse))
+
+	fmt.Println(got)
+
+	var panicErr *PanicError
+	if errors.As(err, &panicErr) {
+		fmt.Printf("%v on item %q\n", panicErr.Value, panicErr.Item)
+	}
+
+	// Output:
+	// [1 3]
+	// empty string on item ""
+}
+
+func TestPanicRecovery(t *testing.T) {
+	// assertPanicError asserts that err is a PanicError with the given value and item
+	assertPanicError := func(t *testing.T, err error, value, item any) {
+		t.Helper()
+
+		var panicErr *PanicError
+		if assert.ErrorAs(t, err, &panicErr) {
+			assert.Equal(t, value, panicErr.Value)
+			assert.Equal(t, item, panicErr.Item)
+			assert.Contains(t, string(panicErr.Stack), "panic_test.go")
+		}
+	}
+
+	t.Run("for each output", func(t *testing.T) {
+		ctx := context.Background()
+
+		f := ForEachOutput(func(ctx context.Context, n int, out chan<- int, errs chan<- error) {
+			if n == 2 {
+				panic("boom")
+			}
+			out <- n
+		}, ForEachOutputRecover())
+
+		got, err := RunCollect(ctx, Pipe(Of(1, 2, 3), f))
+
+		assert.Equal(t, []int{1, 3}, got)
+		assertPanicError(t, err, "boom", 2)
+		assert.EqualError(t, err, "panic: boom")
+	})
+
+	t.Run("for each output with preserve order", func(t *testing.T) {
+		ctx := context.Background()
+
+		f := ForEachOutput(func(ctx context.Context, n int, out chan<- int, errs chan<- error) {
+			if n == 2 {
+				panic("boom")
+			}
+			out <- n
+		}, ForEachOutputRecover(), ForEachOutputPoolSize(2), ForEachOutputPreserveOrder())
+
+		got, err := RunCollect(ctx, Pipe(Of(1, 2, 3), f))
+
+		assert.Equal(t, []int{1, 3}, got)
+		assertPanicError(t, err, "boom", 2)
+	})
+
+	t.Run("map", func(t *testing.T) {
+		ctx := context.Background()
+
+		errBoom := errors.New("boom")
+
+		m := Map(func(ctx context.Context, n int) (int, error) {
+			if n == 2 {
+				panic(errBoom)
+			}
+			return n, nil
+		}, MapRecover())
+
+		got, err := RunCollect(ctx, Pipe(Of(1, 2, 3), m))
+
+		assert.Equal(t, []int{1, 3}, got)
+		assertPanicError(t, err, errBoom, 2)
+		assert.ErrorIs(t, err, errBoom)
+	})
+
+	t.Run("filter and filter map", func(t *testing.T) {
+		ctx := context.Background()
+
+		f := Filter(func(ctx context.Context, n int) (bool, error) {
+			if n == 2 {
+				panic("filter")
+			}
+			return true, nil
+		}, FilterRecover())
+
+		fm := FilterMap(func(ctx context.Context, n int) (bool, int, error) {
+			if n == 3 {
+				panic("filter map")
+			}
+			return true, n, nil
+		}, FilterMapRecover())
+
+		got, err := RunCollect(ctx, Pipe3(Of(1, 2, 3, 4), f, fm))
+
+		assert.Equal(t, []int{1, 4}, got)
+		assert.EqualError(t, err, "panic: filter\npanic: filter map")
+	})
+
+	t.Run("do", func(t *testing.T) {
+		ctx := context.Background()
+
+		var count atomic.Int32
+		d := Do(func(ctx context.Context, n int) error {
+			if n == 2 {
+				panic("boom")
+			}
+			count.Add(1)
+			return nil
+		}, DoRecover())
+
+		err := Run(ctx, Pipe(Of(1, 2, 3), d))
+
+		assert.Equal(t, int32(2), count.Load())
+		assertPanicError(t, err, "boom", 2)
+	})
+
+	t.Run("from func", func(t *testing.T) {
+		ctx := context.Background()
+
+		var n atomic.Int32
+		g := FromFunc(func(ctx context.Context) (int, bool, error) {
+			v := n.Add(1)
+			if v == 2 {
+				panic("boom")
+			}
+			return int(v), v < 4, nil
+		}, FromFuncRecover())
+
+		got, err := RunCollect(ctx, g)
+
+		assert.Equal(t, []int{1, 3}, got)
+		assertPanicError(t, err, "boom", nil)
+		assert.EqualError(t, err, "FromFunc: panic: boom")
+	})
+
+	t.Run("stop with error policy", func(t *testing.T) {
+		ctx := context.Background()
+
+		var count atomic.Int32
+		d := Do(func(ctx context.Context, n int) error {
+			if n == 2 {
+				panic("boom")
+			}
+			count.Add(1)
+			return nil
+		}, DoRecover())
+
+		// The generator blocks after the first items, until it's stopped
+		var n atomic.Int32
+		g := FromFunc(func(ctx context.Context) (int, bool, error) {
+			if v := n.Add(1); v <= 2 {
+				return int(v), true, nil
+			}
+			<-ctx.Done()
+			return 0, false, nil
+		})
+
+		err := Run(ctx, Pipe(g, d), RunErrorPolicy(FailFast()))
+
+		assert.Equal(t, int32(1), count.Load())
+		assertPanicError(t, err, "boom", 2)
+	})
+}
@@ -0,0 +1,103 @@
+package rivo
+
+import (
+	"context"
+	"fmt"
+	"hash/maphash"
+)
+
+// PartitionBy returns a pipeline that distributes the items of the input stream among n lanes according to their key
+// and processes each lane with its own copy of the given pipeline, merging the outputs of all the lanes with Merge.
+// The keys are hashed with hash/maphash, using a new seed for each run of the pipeline.
+// Items with the same key always go to the same lane, so their order is preserved as long as p processes them sequentially,
+// while items with different keys can be processed concurrently.
+// Each lane has its own buffer, see PartitionByLaneBufferSize: when a lane is full, the input stream is blocked until
+// the lane catches up.
+// PartitionBy panics if n is less than 1 or if invalid options are provided.
+func PartitionBy[T, U any, K comparable](key func(T) K, n int, p Pipeline[T, U], opt ...PartitionByOption) Pipeline[T, U] {
+	if n < 1 {
+		panic("n must be greater than 0")
+	}
+
+	o := assertPartitionByOptions(opt)
+
+	return func(ctx context.Context, in Stream[T], errs chan<- error) Stream[U] {
+		lanesCtx := downstreamContext(ctx)
+
+		// The lanes run copies of the same pipeline, so only one is described
+		lane := make(chan T)
+		if DescribeStage(ctx, TopologyNode{Kind: "PartitionBy", PoolSize: n}, in, lane) {
+			return p(lanesCtx, lane, errs)
+		}
+
+		seed := maphash.MakeSeed()
+
+		lanes := make([]chan T, n)
+		outs := make([]<-chan U, n)
+
+		for i := 0; i < n; i++ {
+			lanes[i] = make(chan T, o.laneBufferSize)
+			outs[i] = p(lanesCtx, lanes[i], errs)
+		}
+
+		go func() {
+			defer func() {
+				for i := 0; i < n; i++ {
+					close(lanes[i])
+				}
+			}()
+
+			for item := range OrDone(ctx, in) {
+				lanes[laneOf(seed, key(item), n)] <- item
+			}
+		}()
+
+		return Merge(lanesCtx, outs...)
+	}
+}
+
+func laneOf[K comparable](seed maphash.Seed, key K, n int) int {
+	return int(maphash.Comparable(seed, key) % uint64(n))
+}
+
+type partitionByOptions struct {
+	laneBufferSize int
+}
+
+type PartitionByOption func(*partitionByOptions) error
+
+// PartitionByLaneBufferSize sets the buffer size of each lane, i.e. how many items a lane can lag behind the input stream
+// before blocking it.
+func PartitionByLaneBufferSize(n int) PartitionByOption {
+	return func(o *partitionByOptions) error {
+		if n < 0 {
+			return fmt.Errorf("laneBufferSize must be greater than or equal to 0")
+		}
+		o.laneBufferSize = n
+		return nil
+	}
+}
+
+func newDefaultPartitionByOptions() *partitionByOptions {
+	return &partitionByOptions{
+		laneBufferSize: 0,
+	}
+}
+
+func applyPartitionByOptions(opt []PartitionByOption) (*partitionByOptions, error) {
+	opts := newDefaultPartitionByOptions()
+	for _, o := range opt {
+		if err := o(opts); err != nil {
+			return opts, err
+		}
+	}
+	return opts, nil
+}
+
+func assertPartitionByOptions(opt []PartitionByOption) *partitionByOptions {
+	opts, err := applyPartitionByOptions(opt)
+	if err != nil {
+		panic(fmt.Errorf("invalid partitionBy options: %v", err))
+	}
+	return opts
+}
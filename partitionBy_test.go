@@ -0,0 +1,174 @@
+package rivo_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/agiac/rivo"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type event struct {
+	account string
+	seq     int
+}
+
+func TestPartitionBy(t *testing.T) {
+	t.Run("preserve order per key", func(t *testing.T) {
+		ctx := context.Background()
+
+		var events []event
+		for i := 0; i < 20; i++ {
+			for _, account := range []string{"a", "b", "c", "d"} {
+				events = append(events, event{account: account, seq: i})
+			}
+		}
+
+		process := Map(func(ctx context.Context, e event) (event, error) {
+			time.Sleep(time.Duration(e.seq%3) * time.Millisecond)
+			return e, nil
+		})
+
+		p := PartitionBy(func(e event) string { return e.account }, 3, process)
+
+		got := Collect(Pipe(Of(events...), p)(ctx, nil, nil))
+
+		assert.ElementsMatch(t, events, got)
+
+		last := map[string]int{}
+		for _, e := range got {
+			if seq, ok := last[e.account]; ok {
+				assert.Greater(t, e.seq, seq, "events of account %s out of order", e.account)
+			}
+			last[e.account] = e.seq
+		}
+	})
+
+	t.Run("process lanes concurrently", func(t *testing.T) {
+		ctx := context.Background()
+
+		var running, maxRunning atomic.Int32
+		process := Map(func(ctx context.Context, e event) (event, error) {
+			r := running.Add(1)
+			defer running.Add(-1)
+			for {
+				m := maxRunning.Load()
+				if r <= m || maxRunning.CompareAndSwap(m, r) {
+					break
+				}
+			}
+			time.Sleep(10 * time.Millisecond)
+			return e, nil
+		})
+
+		var events []event
+		for i := 0; i < 16; i++ {
+			events = append(events, event{account: fmt.Sprint(i), seq: i})
+		}
+
+		p := PartitionBy(func(e event) string { return e.account }, 4, process, PartitionByLaneBufferSize(4))
+
+		got := Collect(Pipe(Of(events...), p)(ctx, nil, nil))
+
+		assert.ElementsMatch(t, events, got)
+		assert.Greater(t, maxRunning.Load(), int32(1))
+	})
+
+	t.Run("errors from lanes", func(t *testing.T) {
+		ctx := context.Background()
+
+		fail := Do(func(ctx context.Context, e event) error {
+			return fmt.Errorf("error on %s", e.account)
+		})
+
+		p := PartitionBy(func(e event) string { return e.account }, 2, fail)
+
+		err := Run(ctx, Pipe(Of(event{account: "a"}, event{account: "b"}), p))
+
+		assert.ErrorContains(t, err, "error on a")
+		assert.ErrorContains(t, err, "error on b")
+	})
+
+	t.Run("composite keys", func(t *testing.T) {
+		ctx := context.Background()
+
+		type key struct {
+			account string
+			even    bool
+		}
+
+		var events []event
+		for i := 0; i < 20; i++ {
+			for _, account := range []string{"a", "b"} {
+				events = append(events, event{account: account, seq: i})
+			}
+		}
+
+		process := Map(func(ctx context.Context, e event) (event, error) {
+			time.Sleep(time.Duration(e.seq%3) * time.Millisecond)
+			return e, nil
+		})
+
+		p := PartitionBy(func(e event) key { return key{account: e.account, even: e.seq%2 == 0} }, 3, process)
+
+		got := Collect(Pipe(Of(events...), p)(ctx, nil, nil))
+
+		assert.ElementsMatch(t, events, got)
+
+		last := map[key]int{}
+		for _, e := range got {
+			k := key{account: e.account, even: e.seq%2 == 0}
+			if seq, ok := last[k]; ok {
+				assert.Greater(t, e.seq, seq, "events of key %v out of order", k)
+			}
+			last[k] = e.seq
+		}
+	})
+
+	t.Run("each lane has its own pipeline", func(t *testing.T) {
+		ctx := context.Background()
+
+		var mu sync.Mutex
+		invocations := 0
+
+		p := PartitionBy(func(n int) int { return n }, 3, func(ctx context.Context, in Stream[int], errs chan<- error) Stream[int] {
+			mu.Lock()
+			invocations++
+			mu.Unlock()
+			return in
+		})
+
+		got := Collect(Pipe(Of(1, 2, 3, 4, 5), p)(ctx, nil, nil))
+
+		assert.ElementsMatch(t, []int{1, 2, 3, 4, 5}, got)
+		assert.Equal(t, 3, invocations)
+	})
+
+	t.Run("context cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		in := make(chan int)
+
+		p := PartitionBy(func(n int) int { return n }, 2, Map(func(ctx context.Context, n int) (int, error) {
+			return n, nil
+		}))
+
+		got := Collect(p(ctx, in, nil))
+
+		assert.Empty(t, got)
+	})
+
+	t.Run("invalid number of lanes", func(t *testing.T) {
+		assert.Panics(t, func() {
+			PartitionBy(func(n int) int { return n }, 0, Map(func(ctx context.Context, n int) (int, error) {
+				return n, nil
+			}))
+		})
+	})
+}
@@ -54,3 +54,10 @@ func Pipe9[A, B, C, D, E, F, G, H, I, J any](a Pipeline[A, B], b Pipeline[B, C],
 func Pipe10[A, B, C, D, E, F, G, H, I, J, K any](a Pipeline[A, B], b Pipeline[B, C], c Pipeline[C, D], d Pipeline[D, E], e Pipeline[E, F], f Pipeline[F, G], g Pipeline[G, H], h Pipeline[H, I], i Pipeline[I, J], j Pipeline[J, K]) Pipeline[A, K] {
 	return Pipe9(Pipe2(a, b), c, d, e, f, g, h, i, j)
 }
+
+// downstreamContext returns the context of the sub-pipelines run by a stage on its input, such as the lanes of
+// PartitionBy. Like the stages downstream in Pipe, they are not cancelled, so that they stop once their input is closed
+// and can flush their items.
+func downstreamContext(ctx context.Context) context.Context {
+	return context.WithoutCancel(ctx)
+}
@@ -0,0 +1,223 @@
+package rivo
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"sync/atomic"
+)
+
+// PoolController resizes the pool of workers of a ForEachOutput-based stage while it's running. See Resizable.
+type PoolController struct {
+	mu      sync.Mutex
+	target  int
+	pools   map[*workerPool]struct{}
+	resized chan struct{}
+}
+
+type poolControllerKey struct{}
+
+// poolClaim is the PoolController of a run of a resizable pipeline, which is claimed by its first
+// ForEachOutput-based stage.
+type poolClaim struct {
+	ctrl    *PoolController
+	claimed atomic.Bool
+}
+
+// Resizable returns a pipeline that runs the ForEachOutput-based stage p, such as Map, Filter or Do, and a
+// PoolController to resize its pool of workers while it's running, e.g. to scale it up during traffic spikes.
+// The pool starts with the pool size of the stage, unless the controller has been resized before, and keeps the
+// size set with the controller across runs. When the order is preserved, how many items can be processed ahead of the
+// oldest one follows the size set with the controller too, unless it's set explicitly, e.g. with MapMaxAhead.
+// If p is made of more stages, only the first ForEachOutput-based stage to run is resized.
+func Resizable[T, U any](p Pipeline[T, U]) (Pipeline[T, U], *PoolController) {
+	ctrl := &PoolController{pools: make(map[*workerPool]struct{}), resized: make(chan struct{})}
+
+	return func(ctx context.Context, in Stream[T], errs chan<- error) Stream[U] {
+		return p(context.WithValue(ctx, poolControllerKey{}, &poolClaim{ctrl: ctrl}), in, errs)
+	}, ctrl
+}
+
+// claimPoolController returns the PoolController of the run, if any and not claimed by another stage yet.
+func claimPoolController(ctx context.Context) *PoolController {
+	c, ok := ctx.Value(poolControllerKey{}).(*poolClaim)
+	if !ok || !c.claimed.CompareAndSwap(false, true) {
+		return nil
+	}
+	return c.ctrl
+}
+
+// Resize sets the number of workers of the stage. The new workers start right away, while the extra workers retire
+// once done with the item they are processing, if any, so that Size reaches the new size only after a while.
+// Resize returns an error if n is less than 1.
+func (c *PoolController) Resize(n int) error {
+	if n < 1 {
+		return errors.New("size must be greater than 0")
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.setTarget(n)
+
+	for p := range c.pools {
+		p.resize(n)
+	}
+
+	return nil
+}
+
+// Size returns the number of workers running, including those retiring, across the runs of the stage.
+func (c *PoolController) Size() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	size := 0
+	for p := range c.pools {
+		size += p.size()
+	}
+
+	return size
+}
+
+// Target returns the number of workers set with Resize, or the pool size of the stage if it has run without being
+// resized, or 0 otherwise.
+func (c *PoolController) Target() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.target
+}
+
+// targetChanges returns the target and a channel that is closed when it changes.
+func (c *PoolController) targetChanges() (int, <-chan struct{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.target, c.resized
+}
+
+// setTarget sets the target and notifies the change. It must be called with the lock held.
+func (c *PoolController) setTarget(n int) {
+	c.target = n
+
+	close(c.resized)
+	c.resized = make(chan struct{})
+}
+
+func (c *PoolController) attach(p *workerPool, size int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.target == 0 {
+		c.setTarget(size)
+	}
+
+	c.pools[p] = struct{}{}
+	p.resize(c.target)
+}
+
+func (c *PoolController) detach(p *workerPool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.pools, p)
+}
+
+// workerPool runs the workers of a run of a stage. Each worker gets its index and a channel that is closed when it
+// has to retire, after which it should return as soon as it's done with the current item.
+type workerPool struct {
+	work func(index int, retire <-chan struct{})
+
+	mu      sync.Mutex
+	active  []chan struct{}
+	next    int
+	running int
+	done    chan struct{}
+}
+
+// runWorkerPool runs size workers, or as many as set with the PoolController, if any, until they have all returned.
+func runWorkerPool(ctrl *PoolController, size int, work func(index int, retire <-chan struct{})) {
+	p := &workerPool{work: work, done: make(chan struct{})}
+
+	if ctrl != nil {
+		ctrl.attach(p, size)
+		defer ctrl.detach(p)
+	} else {
+		p.resize(size)
+	}
+
+	<-p.done
+}
+
+// resize starts or retires workers, the latest started first, so that n workers are active.
+// Once all the workers have returned, the pool is done and it's not resized anymore.
+func (p *workerPool) resize(n int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.isDone() {
+		return
+	}
+
+	for len(p.active) < n {
+		retire := make(chan struct{})
+		p.active = append(p.active, retire)
+		p.running++
+
+		go p.run(p.next, retire)
+		p.next++
+	}
+
+	for len(p.active) > n {
+		last := len(p.active) - 1
+		close(p.active[last])
+		p.active = p.active[:last]
+	}
+}
+
+func (p *workerPool) run(index int, retire chan struct{}) {
+	defer func() {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+
+		// The worker may have returned on its own, e.g. because the input stream is closed
+		if i := slices.Index(p.active, retire); i >= 0 {
+			p.active = slices.Delete(p.active, i, i+1)
+		}
+
+		p.running--
+		if p.running == 0 {
+			close(p.done)
+		}
+	}()
+
+	p.work(index, retire)
+}
+
+func (p *workerPool) size() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.running
+}
+
+func (p *workerPool) isDone() bool {
+	select {
+	case <-p.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// retired reports whether the worker has to retire.
+func retired(retire <-chan struct{}) bool {
+	select {
+	case <-retire:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,206 @@
+package rivo_test
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/agiac/rivo"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func ExampleResizable() {
+	ctx := context.Background()
+
+	double, ctrl := Resizable(Map(func(ctx context.Context, n int) (int, error) {
+		return n * 2, nil
+	}, MapPreserveOrder()))
+
+	fmt.Println("target before running:", ctrl.Target())
+
+	_ = ctrl.Resize(4)
+
+	got, _ := RunCollect(ctx, Pipe(Of(1, 2, 3, 4, 5), double))
+
+	fmt.Println(got, "target:", ctrl.Target(), "size:", ctrl.Size())
+
+	// Output:
+	// target before running: 0
+	// [2 4 6 8 10] target: 4 size: 0
+}
+
+// gatedStage returns a Map stage whose items wait for a token from the returned gate, counting the active items.
+func gatedStage(opt ...MapOption) (Pipeline[int, int], chan<- struct{}, *atomic.Int64) {
+	gate := make(chan struct{})
+	active := &atomic.Int64{}
+
+	return Map(func(ctx context.Context, n int) (int, error) {
+		active.Add(1)
+		defer active.Add(-1)
+		<-gate
+		return n, nil
+	}, opt...), gate, active
+}
+
+func TestResizable(t *testing.T) {
+	for _, preserveOrder := range []bool{false, true} {
+		t.Run(fmt.Sprintf("resize a running stage, preserve order %v", preserveOrder), func(t *testing.T) {
+			ctx := context.Background()
+
+			opt := []MapOption{MapPoolSize(3)}
+			if preserveOrder {
+				opt = append(opt, MapPreserveOrder())
+			}
+
+			stage, gate, active := gatedStage(opt...)
+			p, ctrl := Resizable(stage)
+
+			in := make(chan int)
+			out := p(ctx, in, make(chan error))
+
+			for i := 0; i < 3; i++ {
+				in <- i
+			}
+
+			assert.Eventually(t, func() bool { return active.Load() == 3 }, time.Second, time.Millisecond)
+			assert.Equal(t, 3, ctrl.Target())
+			assert.Equal(t, 3, ctrl.Size())
+
+			// The extra workers retire after their current item
+			assert.NoError(t, ctrl.Resize(1))
+			assert.Equal(t, 1, ctrl.Target())
+			assert.Equal(t, 3, ctrl.Size())
+
+			for i := 0; i < 3; i++ {
+				gate <- struct{}{}
+			}
+
+			for i := 0; i < 3; i++ {
+				<-out
+			}
+
+			assert.Eventually(t, func() bool { return ctrl.Size() == 1 }, time.Second, time.Millisecond)
+
+			// A single worker is left, so the next item waits for the current one
+			go func() {
+				in <- 3
+				in <- 4
+			}()
+
+			assert.Eventually(t, func() bool { return active.Load() == 1 }, time.Second, time.Millisecond)
+			time.Sleep(10 * time.Millisecond)
+			assert.Equal(t, int64(1), active.Load())
+
+			// The new workers start right away
+			assert.NoError(t, ctrl.Resize(2))
+			assert.Equal(t, 2, ctrl.Target())
+
+			assert.Eventually(t, func() bool { return active.Load() == 2 }, time.Second, time.Millisecond)
+			assert.Equal(t, 2, ctrl.Size())
+
+			close(in)
+
+			for i := 0; i < 2; i++ {
+				gate <- struct{}{}
+			}
+
+			var got []int
+			for v := range out {
+				got = append(got, v)
+			}
+
+			assert.ElementsMatch(t, []int{3, 4}, got)
+			assert.Equal(t, 0, ctrl.Size())
+			assert.Equal(t, 2, ctrl.Target())
+		})
+	}
+
+	t.Run("keep the size across runs", func(t *testing.T) {
+		ctx := context.Background()
+
+		stage, gate, active := gatedStage()
+		p, ctrl := Resizable(stage)
+
+		assert.Equal(t, 0, ctrl.Target())
+		assert.NoError(t, ctrl.Resize(2))
+
+		for run := 0; run < 2; run++ {
+			in := make(chan int)
+			out := p(ctx, in, make(chan error))
+
+			in <- 1
+			in <- 2
+
+			assert.Eventually(t, func() bool { return active.Load() == 2 }, time.Second, time.Millisecond)
+			assert.Equal(t, 2, ctrl.Size())
+
+			close(in)
+			gate <- struct{}{}
+			gate <- struct{}{}
+
+			for range out {
+			}
+
+			assert.Equal(t, 0, ctrl.Size())
+		}
+	})
+
+	t.Run("scale the items processed ahead with the size, preserve order", func(t *testing.T) {
+		ctx := context.Background()
+
+		stage, gate, active := gatedStage(MapPreserveOrder())
+		p, ctrl := Resizable(stage)
+
+		in := make(chan int)
+		out := p(ctx, in, make(chan error))
+
+		go func() {
+			defer close(in)
+			for i := 0; i < 4; i++ {
+				in <- i
+			}
+		}()
+
+		assert.Eventually(t, func() bool { return active.Load() == 1 }, time.Second, time.Millisecond)
+
+		// While the first item is pending, the new workers process the following ones
+		assert.NoError(t, ctrl.Resize(4))
+		assert.Eventually(t, func() bool { return active.Load() == 4 }, time.Second, time.Millisecond)
+
+		go func() {
+			for i := 0; i < 4; i++ {
+				gate <- struct{}{}
+			}
+		}()
+
+		var got []int
+		for v := range out {
+			got = append(got, v)
+		}
+
+		assert.Equal(t, []int{0, 1, 2, 3}, got)
+	})
+
+	t.Run("resize only the first stage", func(t *testing.T) {
+		ctx := context.Background()
+
+		identity := func(ctx context.Context, n int) (int, error) { return n, nil }
+
+		p, ctrl := Resizable(Pipe(Map(identity, MapPoolSize(2)), Map(identity, MapPoolSize(5))))
+
+		got, err := RunCollect(ctx, Pipe(Of(1, 2, 3), p))
+		assert.NoError(t, err)
+		assert.ElementsMatch(t, []int{1, 2, 3}, got)
+		assert.Equal(t, 2, ctrl.Target())
+	})
+
+	t.Run("invalid size", func(t *testing.T) {
+		_, ctrl := Resizable(Map(func(ctx context.Context, n int) (int, error) { return n, nil }))
+
+		assert.EqualError(t, ctrl.Resize(0), "size must be greater than 0")
+		assert.Equal(t, 0, ctrl.Target())
+	})
+}
@@ -0,0 +1,50 @@
+package rivo
+
+import (
+	"context"
+	"runtime/pprof"
+	"strconv"
+)
+
+type pipelineNameKey struct{}
+
+// WithPipelineName returns a pipeline that runs p with the given name, which labels the goroutines of its stages in
+// the CPU and goroutine profiles, next to the name of each stage, set with Named, or its kind, and the index of the
+// worker. The goroutines coordinating the workers of a stage, as well as those of the stages without workers, such as
+// Batch and Tee, have no worker label. The goroutines of the stages are labeled also without a pipeline name.
+//
+// The labels are "rivo.pipeline", "rivo.stage" and "rivo.worker", so that e.g. the goroutine profile can be filtered
+// with go tool pprof -tagfocus rivo.stage=parse.
+// WithPipelineName panics if the name is empty.
+func WithPipelineName[T, U any](name string, p Pipeline[T, U]) Pipeline[T, U] {
+	if name == "" {
+		panic("name must not be empty")
+	}
+
+	return func(ctx context.Context, in Stream[T], errs chan<- error) Stream[U] {
+		return p(context.WithValue(ctx, pipelineNameKey{}, name), in, errs)
+	}
+}
+
+// labelGoroutine labels the current goroutine, which runs a stage of the given kind, with the name of the pipeline,
+// if any, the name of the stage, or its kind if not named, and the index of the worker, unless it's negative, as for
+// the goroutines coordinating the workers. The labels set on ctx, e.g. with pprof.Do, are kept.
+// The goroutines started by the current one inherit its labels.
+func labelGoroutine(ctx context.Context, kind string, worker int) {
+	stage := stageName(ctx)
+	if stage == "" {
+		stage = kind
+	}
+
+	labels := []string{"rivo.stage", stage}
+
+	if pipeline, ok := ctx.Value(pipelineNameKey{}).(string); ok {
+		labels = append(labels, "rivo.pipeline", pipeline)
+	}
+
+	if worker >= 0 {
+		labels = append(labels, "rivo.worker", strconv.Itoa(worker))
+	}
+
+	pprof.SetGoroutineLabels(pprof.WithLabels(ctx, pprof.Labels(labels...)))
+}
@@ -0,0 +1,181 @@
+package rivo_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"runtime/pprof"
+	"strings"
+	"sync"
+	"testing"
+
+	. "github.com/agiac/rivo"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// goroutineLabels returns the labels of the goroutines running stages of the given pipeline, as "stage/worker" strings.
+func goroutineLabels(t *testing.T, pipeline string) map[string]bool {
+	var buf bytes.Buffer
+	err := pprof.Lookup("goroutine").WriteTo(&buf, 1)
+	assert.NoError(t, err)
+
+	labels := make(map[string]bool)
+
+	scanner := bufio.NewScanner(&buf)
+	for scanner.Scan() {
+		line, ok := strings.CutPrefix(scanner.Text(), "# labels: ")
+		if !ok {
+			continue
+		}
+
+		var l map[string]string
+		assert.NoError(t, json.Unmarshal([]byte(line), &l))
+
+		if l["rivo.pipeline"] != pipeline {
+			continue
+		}
+
+		worker, ok := l["rivo.worker"]
+		if !ok {
+			worker = "-"
+		}
+
+		labels[l["rivo.stage"]+"/"+worker] = true
+	}
+
+	return labels
+}
+
+// blocker blocks the items until released, counting the items being blocked.
+type blocker struct {
+	started chan struct{}
+	release chan struct{}
+	once    sync.Once
+}
+
+func newBlocker(n int) *blocker {
+	return &blocker{started: make(chan struct{}, n), release: make(chan struct{})}
+}
+
+func (b *blocker) block() {
+	// Once released, the items are not counted anymore
+	select {
+	case b.started <- struct{}{}:
+	case <-b.release:
+	}
+	<-b.release
+}
+
+func (b *blocker) wait(n int) {
+	for i := 0; i < n; i++ {
+		<-b.started
+	}
+}
+
+func (b *blocker) unblock() {
+	b.once.Do(func() { close(b.release) })
+}
+
+func TestWithPipelineName(t *testing.T) {
+	t.Run("label the workers of ForEachOutput", func(t *testing.T) {
+		ctx := context.Background()
+
+		b := newBlocker(3)
+		defer b.unblock()
+
+		double := Named("double", Map(func(ctx context.Context, n int) (int, error) {
+			b.block()
+			return n * 2, nil
+		}, MapPoolSize(3), MapPreserveOrder()))
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			_, _ = RunCollect(ctx, WithPipelineName("orders", Pipe(Of(1, 2, 3), double)))
+		}()
+
+		b.wait(3)
+
+		labels := goroutineLabels(t, "orders")
+		assert.True(t, labels["double/-"])
+		assert.True(t, labels["double/0"])
+		assert.True(t, labels["double/1"])
+		assert.True(t, labels["double/2"])
+
+		b.unblock()
+		<-done
+	})
+
+	t.Run("label the goroutines of the other stages", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		b := newBlocker(2)
+		defer b.unblock()
+
+		numbers := FromFunc(func(ctx context.Context) (int, bool, error) {
+			return 1, true, nil
+		}, FromFuncPoolSize(2))
+
+		merge := func(ctx context.Context, in Stream[int], errs chan<- error) Stream[int] {
+			a, b := TeeStream(ctx, in)
+			return Merge(ctx, a, b)
+		}
+
+		sink := Do(func(ctx context.Context, batch []int) error {
+			b.block()
+			return nil
+		})
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			_ = Run(ctx, WithPipelineName("numbers", Pipe4(numbers, merge, Batch[int](2), Connect(sink, sink))))
+		}()
+
+		b.wait(2)
+
+		labels := goroutineLabels(t, "numbers")
+		for _, l := range []string{"FromFunc/-", "FromFunc/0", "FromFunc/1", "Tee/-", "Merge/-", "Merge/0", "Merge/1", "Batch/-", "Connect/-", "Connect/0", "Connect/1", "Do/0"} {
+			assert.True(t, labels[l], l)
+		}
+
+		cancel()
+		b.unblock()
+		<-done
+	})
+
+	t.Run("label the goroutines without a pipeline name", func(t *testing.T) {
+		ctx := context.Background()
+
+		b := newBlocker(1)
+		defer b.unblock()
+
+		identity := Map(func(ctx context.Context, n int) (int, error) {
+			b.block()
+			return n, nil
+		})
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			_, _ = RunCollect(ctx, Pipe(Of(1), identity))
+		}()
+
+		b.wait(1)
+
+		labels := goroutineLabels(t, "")
+		assert.True(t, labels["Map/0"])
+
+		b.unblock()
+		<-done
+	})
+
+	t.Run("empty name", func(t *testing.T) {
+		assert.PanicsWithValue(t, "name must not be empty", func() {
+			WithPipelineName("", Map(func(ctx context.Context, n int) (int, error) { return n, nil }))
+		})
+	})
+}
@@ -0,0 +1,228 @@
+package rivo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// RateLimit returns a pipeline that emits the items of the input stream at most at the given rate, in items per second,
+// allowing bursts of up to burst items. It's implemented as a token bucket: while there are no tokens available, the
+// pipeline stops reading from the input stream, so that the upstream stages are blocked.
+// RateLimit panics if rate is not positive, if burst is less than 1 or if invalid options are provided.
+func RateLimit[T any](rate float64, burst int, opt ...RateLimitOption) Pipeline[T, T] {
+	b := newTokenBucket(rate, burst)
+
+	return rateLimit[T]("RateLimit", func(T) *tokenBucket { return b }, opt)
+}
+
+// RateLimitBy is like RateLimit, but it limits the items with the same key independently of each other.
+// Since the items are emitted in order, an item waiting for its key blocks the following ones too.
+// RateLimitBy panics if rate is not positive, if burst is less than 1 or if invalid options are provided.
+func RateLimitBy[T any, K comparable](key func(T) K, rate float64, burst int, opt ...RateLimitOption) Pipeline[T, T] {
+	b := newKeyedTokenBuckets[K](rate, burst)
+
+	return rateLimit("RateLimitBy", func(v T) *tokenBucket { return b.get(key(v)) }, opt)
+}
+
+func rateLimit[T any](kind string, bucket func(T) *tokenBucket, opt []RateLimitOption) Pipeline[T, T] {
+	o := assertRateLimitOptions(opt)
+
+	return func(ctx context.Context, in Stream[T], errs chan<- error) Stream[T] {
+		out := make(chan T, o.bufferSize)
+
+		if DescribeStage(ctx, TopologyNode{Kind: kind, BufferSize: o.bufferSize}, in, out) {
+			return out
+		}
+
+		go func() {
+			defer close(out)
+
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case v, ok := <-in:
+					if !ok {
+						return
+					}
+
+					if err := bucket(v).wait(ctx); err != nil {
+						return
+					}
+
+					select {
+					case <-ctx.Done():
+						return
+					case out <- v:
+					}
+				}
+			}
+		}()
+
+		return out
+	}
+}
+
+func validateRateLimit(rate float64, burst int) error {
+	if rate <= 0 {
+		return errors.New("rate must be greater than 0")
+	}
+
+	if burst < 1 {
+		return errors.New("burst must be greater than 0")
+	}
+
+	return nil
+}
+
+// tokenBucket is a token bucket rate limiter, safe for concurrent use.
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate float64, burst int) *tokenBucket {
+	if err := validateRateLimit(rate, burst); err != nil {
+		panic(err.Error())
+	}
+
+	return &tokenBucket{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+func (b *tokenBucket) refill(now time.Time) {
+	b.tokens = min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+}
+
+// wait takes a token from the bucket, waiting until one is available or the context is done.
+// Tokens are reserved in order of arrival, so that concurrent callers are served fairly.
+func (b *tokenBucket) wait(ctx context.Context) error {
+	b.mu.Lock()
+	b.refill(time.Now())
+	b.tokens--
+	delay := time.Duration(-b.tokens / b.rate * float64(time.Second))
+	b.mu.Unlock()
+
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		// Give back the reserved token
+		b.mu.Lock()
+		b.tokens++
+		b.mu.Unlock()
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// full reports whether the bucket is full, i.e. whether it behaves as a new bucket.
+func (b *tokenBucket) full(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refill(now)
+	return b.tokens >= b.burst
+}
+
+// keyedTokenBuckets is a set of token buckets, one for each key.
+// Full buckets are removed from time to time, so that the set doesn't grow indefinitely.
+type keyedTokenBuckets[K comparable] struct {
+	mu      sync.Mutex
+	rate    float64
+	burst   int
+	buckets map[K]*tokenBucket
+	pruneAt int
+}
+
+func newKeyedTokenBuckets[K comparable](rate float64, burst int) *keyedTokenBuckets[K] {
+	// Validate the parameters eagerly
+	newTokenBucket(rate, burst)
+
+	return &keyedTokenBuckets[K]{
+		rate:    rate,
+		burst:   burst,
+		buckets: make(map[K]*tokenBucket),
+		pruneAt: 64,
+	}
+}
+
+func (k *keyedTokenBuckets[K]) get(key K) *tokenBucket {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if b, ok := k.buckets[key]; ok {
+		return b
+	}
+
+	if len(k.buckets) >= k.pruneAt {
+		now := time.Now()
+		for key, b := range k.buckets {
+			if b.full(now) {
+				delete(k.buckets, key)
+			}
+		}
+		k.pruneAt = max(64, 2*len(k.buckets))
+	}
+
+	b := newTokenBucket(k.rate, k.burst)
+	k.buckets[key] = b
+
+	return b
+}
+
+type rateLimitOptions struct {
+	bufferSize int
+}
+
+type RateLimitOption func(*rateLimitOptions) error
+
+func RateLimitBufferSize(n int) RateLimitOption {
+	return func(o *rateLimitOptions) error {
+		if n < 0 {
+			return fmt.Errorf("bufferSize must be greater than or equal to 0")
+		}
+		o.bufferSize = n
+		return nil
+	}
+}
+
+func newDefaultRateLimitOptions() *rateLimitOptions {
+	return &rateLimitOptions{
+		bufferSize: 0,
+	}
+}
+
+func applyRateLimitOptions(opt []RateLimitOption) (*rateLimitOptions, error) {
+	opts := newDefaultRateLimitOptions()
+	for _, o := range opt {
+		if err := o(opts); err != nil {
+			return opts, err
+		}
+	}
+	return opts, nil
+}
+
+func assertRateLimitOptions(opt []RateLimitOption) *rateLimitOptions {
+	opts, err := applyRateLimitOptions(opt)
+	if err != nil {
+		panic(fmt.Errorf("invalid rateLimit options: %v", err))
+	}
+	return opts
+}
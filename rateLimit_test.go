@@ -0,0 +1,230 @@
+package rivo_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	. "github.com/agiac/rivo"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func ExampleRateLimit() {
+	ctx := context.Background()
+
+	// Emit at most 100 items per second, with bursts of up to 2 items
+	p := Pipe(Of(1, 2, 3, 4, 5), RateLimit[int](100, 2))
+
+	for n := range p(ctx, nil, nil) {
+		fmt.Println(n)
+	}
+
+	// Output:
+	// 1
+	// 2
+	// 3
+	// 4
+	// 5
+}
+
+func TestRateLimit(t *testing.T) {
+	t.Run("limit the rate", func(t *testing.T) {
+		ctx := context.Background()
+
+		start := time.Now()
+
+		got := Collect(Pipe(Of(1, 2, 3, 4, 5, 6), RateLimit[int](50, 2))(ctx, nil, nil))
+
+		// The first 2 items are a burst, the other 4 are spaced by 20ms
+		assert.Equal(t, []int{1, 2, 3, 4, 5, 6}, got)
+		assert.GreaterOrEqual(t, time.Since(start), 70*time.Millisecond)
+	})
+
+	t.Run("block upstream", func(t *testing.T) {
+		ctx := context.Background()
+
+		in := make(chan int)
+		out := RateLimit[int](10, 1)(ctx, in, nil)
+
+		got := make(chan []int)
+		go func() {
+			got <- Collect(out)
+		}()
+
+		// The first item takes the only token and the second one waits for the next, 100ms later
+		in <- 1
+		in <- 2
+
+		select {
+		case in <- 3:
+			assert.Fail(t, "upstream should be blocked")
+		case <-time.After(30 * time.Millisecond):
+		}
+
+		close(in)
+
+		assert.Equal(t, []int{1, 2}, <-got)
+	})
+
+	t.Run("with context cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+
+		in := make(chan int, 2)
+		in <- 1
+		in <- 2
+
+		out := RateLimit[int](0.1, 1)(ctx, in, nil)
+
+		assert.Equal(t, 1, <-out)
+
+		time.AfterFunc(10*time.Millisecond, cancel)
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			Collect(out)
+		}()
+
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			assert.Fail(t, "output should be closed when the context is cancelled")
+		}
+	})
+
+	t.Run("with buffer size", func(t *testing.T) {
+		ctx := context.Background()
+
+		out := Pipe(Of(1, 2, 3), RateLimit[int](1000, 3, RateLimitBufferSize(3)))(ctx, nil, nil)
+
+		time.Sleep(10 * time.Millisecond)
+
+		assert.Equal(t, 3, len(out))
+	})
+}
+
+func TestRateLimitBy(t *testing.T) {
+	t.Run("limit each key independently", func(t *testing.T) {
+		ctx := context.Background()
+
+		key := func(e event) string { return e.account }
+
+		start := time.Now()
+
+		got := Collect(Pipe(
+			Of(event{"a", 1}, event{"b", 1}, event{"c", 1}, event{"a", 2}),
+			RateLimitBy(key, 20, 1),
+		)(ctx, nil, nil))
+
+		// Only the second item of "a" has to wait for a token
+		assert.Equal(t, []event{{"a", 1}, {"b", 1}, {"c", 1}, {"a", 2}}, got)
+		elapsed := time.Since(start)
+		assert.GreaterOrEqual(t, elapsed, 40*time.Millisecond)
+		assert.Less(t, elapsed, 100*time.Millisecond)
+	})
+
+	t.Run("with a comparable key", func(t *testing.T) {
+		ctx := context.Background()
+
+		key := func(e event) int { return e.seq }
+
+		start := time.Now()
+
+		got := Collect(Pipe(
+			Of(event{"a", 1}, event{"b", 2}, event{"c", 1}),
+			RateLimitBy(key, 20, 1),
+		)(ctx, nil, nil))
+
+		// Only the item of "c" shares its key with an earlier item
+		assert.Equal(t, []event{{"a", 1}, {"b", 2}, {"c", 1}}, got)
+		elapsed := time.Since(start)
+		assert.GreaterOrEqual(t, elapsed, 40*time.Millisecond)
+		assert.Less(t, elapsed, 100*time.Millisecond)
+	})
+}
+
+func TestRateLimitOptions(t *testing.T) {
+	t.Run("map", func(t *testing.T) {
+		ctx := context.Background()
+
+		start := time.Now()
+
+		double := Map(func(ctx context.Context, n int) (int, error) {
+			return n * 2, nil
+		}, MapPoolSize(3), MapRateLimit(50, 1))
+
+		got := Collect(Pipe(Of(1, 2, 3, 4), double)(ctx, nil, nil))
+
+		// The limit is shared by the workers
+		assert.ElementsMatch(t, []int{2, 4, 6, 8}, got)
+		assert.GreaterOrEqual(t, time.Since(start), 55*time.Millisecond)
+	})
+
+	t.Run("map with preserve order", func(t *testing.T) {
+		ctx := context.Background()
+
+		start := time.Now()
+
+		double := Map(func(ctx context.Context, n int) (int, error) {
+			return n * 2, nil
+		}, MapPoolSize(3), MapPreserveOrder(), MapRateLimit(50, 1))
+
+		got := Collect(Pipe(Of(1, 2, 3, 4), double)(ctx, nil, nil))
+
+		assert.Equal(t, []int{2, 4, 6, 8}, got)
+		assert.GreaterOrEqual(t, time.Since(start), 55*time.Millisecond)
+	})
+
+	t.Run("do", func(t *testing.T) {
+		ctx := context.Background()
+
+		start := time.Now()
+
+		d := Do(func(ctx context.Context, n int) error {
+			return nil
+		}, DoRateLimit(50, 2))
+
+		Collect(Pipe(Of(1, 2, 3, 4), d)(ctx, nil, nil))
+
+		assert.GreaterOrEqual(t, time.Since(start), 35*time.Millisecond)
+	})
+
+	t.Run("filter map", func(t *testing.T) {
+		ctx := context.Background()
+
+		start := time.Now()
+
+		even := FilterMap(func(ctx context.Context, n int) (bool, int, error) {
+			return n%2 == 0, n, nil
+		}, FilterMapRateLimit(50, 2))
+
+		got := Collect(Pipe(Of(1, 2, 3, 4), even)(ctx, nil, nil))
+
+		assert.Equal(t, []int{2, 4}, got)
+		assert.GreaterOrEqual(t, time.Since(start), 35*time.Millisecond)
+	})
+
+	t.Run("shared across runs", func(t *testing.T) {
+		ctx := context.Background()
+
+		limited := RateLimit[int](20, 1)
+
+		Collect(Pipe(Of(1), limited)(ctx, nil, nil))
+
+		start := time.Now()
+
+		Collect(Pipe(Of(2), limited)(ctx, nil, nil))
+
+		assert.GreaterOrEqual(t, time.Since(start), 40*time.Millisecond)
+	})
+}
+
+func TestRateLimitPanics(t *testing.T) {
+	assert.Panics(t, func() { RateLimit[int](0, 1) })
+	assert.Panics(t, func() { RateLimit[int](1, 0) })
+	assert.Panics(t, func() { RateLimitBy(func(n int) int { return n }, -1, 1) })
+	assert.Panics(t, func() { Map(func(ctx context.Context, n int) (int, error) { return n, nil }, MapRateLimit(0, 1)) })
+	assert.Panics(t, func() { Do(func(ctx context.Context, n int) error { return nil }, DoRateLimit(1, 0)) })
+}
@@ -0,0 +1,103 @@
+package rivo
+
+import (
+	"context"
+	"fmt"
+)
+
+// Reduce returns a pipeline that accumulates the items of the input stream, using the first item as the initial value,
+// and emits the accumulated value once the input stream is closed. Nothing is emitted if the input stream is empty.
+// If the function returns an error, it's sent to the error channel and the item is skipped.
+// If the context is cancelled, nothing is emitted, unless ReduceEmitPartial is used.
+func Reduce[T any](f func(ctx context.Context, acc T, val T) (T, error), opt ...ReduceOption) Pipeline[T, T] {
+	o := assertReduceOptions(opt)
+
+	return func(ctx context.Context, in Stream[T], errs chan<- error) Stream[T] {
+		// The output is buffered, so that the result can always be emitted, even after the context is cancelled.
+		out := make(chan T, 1)
+
+		if DescribeStage(ctx, TopologyNode{Kind: "Reduce", BufferSize: 1}, in, out) {
+			return out
+		}
+
+		go func() {
+			defer close(out)
+
+			var acc T
+			hasAcc := false
+
+			for {
+				select {
+				case <-ctx.Done():
+					if o.emitPartial && hasAcc {
+						out <- acc
+					}
+					return
+				case v, ok := <-in:
+					if !ok {
+						if hasAcc {
+							out <- acc
+						}
+						return
+					}
+
+					if !hasAcc {
+						acc, hasAcc = v, true
+						continue
+					}
+
+					next, err := f(ctx, acc, v)
+					if err != nil {
+						select {
+						case <-ctx.Done():
+						case errs <- err:
+						}
+						continue
+					}
+
+					acc = next
+				}
+			}
+		}()
+
+		return out
+	}
+}
+
+type reduceOptions struct {
+	emitPartial bool
+}
+
+type ReduceOption func(*reduceOptions) error
+
+// ReduceEmitPartial configures Reduce to emit the value accumulated so far when the context is cancelled.
+func ReduceEmitPartial() ReduceOption {
+	return func(o *reduceOptions) error {
+		o.emitPartial = true
+		return nil
+	}
+}
+
+func newDefaultReduceOptions() *reduceOptions {
+	return &reduceOptions{
+		emitPartial: false,
+	}
+}
+
+func applyReduceOptions(opt []ReduceOption) (*reduceOptions, error) {
+	opts := newDefaultReduceOptions()
+	for _, o := range opt {
+		if err := o(opts); err != nil {
+			return opts, err
+		}
+	}
+	return opts, nil
+}
+
+func assertReduceOptions(opt []ReduceOption) *reduceOptions {
+	opts, err := applyReduceOptions(opt)
+	if err != nil {
+		panic(fmt.Errorf("invalid reduce options: %v", err))
+	}
+	return opts
+}
@@ -0,0 +1,93 @@
+package rivo_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	. "github.com/agiac/rivo"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func ExampleReduce() {
+	ctx := context.Background()
+
+	in := Of(1, 2, 3, 4, 5)
+
+	sum := Reduce(func(ctx context.Context, acc int, n int) (int, error) {
+		return acc + n, nil
+	})
+
+	for n := range Pipe(in, sum)(ctx, nil, nil) {
+		fmt.Println(n)
+	}
+
+	// Output:
+	// 15
+}
+
+func TestReduce(t *testing.T) {
+	maxFn := func(ctx context.Context, acc int, n int) (int, error) {
+		return max(acc, n), nil
+	}
+
+	t.Run("reduce all items", func(t *testing.T) {
+		ctx := context.Background()
+
+		got := Collect(Pipe(Of(3, 7, 2), Reduce(maxFn))(ctx, nil, nil))
+
+		assert.Equal(t, []int{7}, got)
+	})
+
+	t.Run("empty stream", func(t *testing.T) {
+		ctx := context.Background()
+
+		got := Collect(Pipe(Of[int](), Reduce(maxFn))(ctx, nil, nil))
+
+		assert.Empty(t, got)
+	})
+
+	t.Run("skip items with errors", func(t *testing.T) {
+		ctx := context.Background()
+
+		r := Reduce(func(ctx context.Context, acc int, n int) (int, error) {
+			if n == 2 {
+				return 0, errors.New("error on 2")
+			}
+			return acc + n, nil
+		})
+
+		got, err := RunCollect(ctx, Pipe(Of(1, 2, 3), r))
+
+		assert.Equal(t, []int{4}, got)
+		assert.EqualError(t, err, "error on 2")
+	})
+
+	t.Run("context cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+
+		in := make(chan int)
+		out := Reduce(maxFn)(ctx, in, nil)
+
+		in <- 1
+		in <- 2
+		cancel()
+
+		assert.Empty(t, Collect(out))
+	})
+
+	t.Run("context cancelled with emit partial", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+
+		in := make(chan int)
+		out := Reduce(maxFn, ReduceEmitPartial())(ctx, in, nil)
+
+		in <- 1
+		in <- 2
+		cancel()
+
+		assert.Equal(t, []int{2}, Collect(out))
+	})
+}
@@ -0,0 +1,232 @@
+package rivo
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"time"
+)
+
+// RetryError is the error returned by a retried function whose attempts all failed.
+// It wraps the errors of every attempt, so that errors.Is and errors.As match any of them.
+type RetryError struct {
+	Errs []error
+}
+
+func (e *RetryError) Error() string {
+	if len(e.Errs) == 1 {
+		return fmt.Sprintf("%v (after 1 attempt)", e.Errs[0])
+	}
+	return fmt.Sprintf("%v (after %d attempts)", e.Errs[len(e.Errs)-1], len(e.Errs))
+}
+
+func (e *RetryError) Unwrap() []error {
+	return e.Errs
+}
+
+// Retry returns a function that calls f until it succeeds, up to the maximum number of attempts,
+// waiting between the attempts with an exponential backoff. Only the errors accepted by RetryIf are retried and
+// nothing is retried once the context is done. If every attempt fails, the function returns a RetryError.
+// By default, f is attempted 3 times, with a backoff starting from 100ms and doubling up to 10s.
+// Retry panics if invalid options are provided.
+func Retry[T, U any](f func(context.Context, T) (U, error), opt ...RetryOption) func(context.Context, T) (U, error) {
+	return retryFunc(assertRetryOptions(opt), f)
+}
+
+func retryFunc[T, U any](o *retryOptions, f func(context.Context, T) (U, error)) func(context.Context, T) (U, error) {
+	return func(ctx context.Context, val T) (U, error) {
+		var res U
+		err := o.do(ctx, func(ctx context.Context) error {
+			var err error
+			res, err = f(ctx, val)
+			return err
+		})
+		return res, err
+	}
+}
+
+func retryDoFunc[T any](o *retryOptions, f func(context.Context, T) error) func(context.Context, T) error {
+	return func(ctx context.Context, val T) error {
+		return o.do(ctx, func(ctx context.Context) error {
+			return f(ctx, val)
+		})
+	}
+}
+
+func retryFilterMapFunc[T, U any](o *retryOptions, f func(context.Context, T) (bool, U, error)) func(context.Context, T) (bool, U, error) {
+	return func(ctx context.Context, val T) (bool, U, error) {
+		var keep bool
+		var res U
+		err := o.do(ctx, func(ctx context.Context) error {
+			var err error
+			keep, res, err = f(ctx, val)
+			return err
+		})
+		return keep, res, err
+	}
+}
+
+// do calls attempt until it succeeds or it must give up.
+func (o *retryOptions) do(ctx context.Context, attempt func(context.Context) error) error {
+	var errs []error
+
+	for n := 1; ; n++ {
+		err := o.attempt(ctx, attempt)
+		if err == nil {
+			return nil
+		}
+
+		errs = append(errs, err)
+
+		if n == o.maxAttempts || ctx.Err() != nil || !o.retryIf(err) {
+			return &RetryError{Errs: errs}
+		}
+
+		timer := time.NewTimer(o.backoff(n))
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return &RetryError{Errs: append(errs, ctx.Err())}
+		case <-timer.C:
+		}
+	}
+}
+
+func (o *retryOptions) attempt(ctx context.Context, attempt func(context.Context) error) error {
+	if o.attemptTimeout == 0 {
+		return attempt(ctx)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, o.attemptTimeout)
+	defer cancel()
+
+	return attempt(ctx)
+}
+
+// backoff returns the time to wait after the nth failed attempt.
+func (o *retryOptions) backoff(n int) time.Duration {
+	d := float64(o.initialBackoff)
+	for i := 1; i < n && d < float64(o.maxBackoff); i++ {
+		d *= o.multiplier
+	}
+	d = min(d, float64(o.maxBackoff))
+
+	if o.jitter > 0 {
+		d -= d * o.jitter * rand.Float64()
+	}
+
+	return time.Duration(d)
+}
+
+type retryOptions struct {
+	maxAttempts    int
+	initialBackoff time.Duration
+	maxBackoff     time.Duration
+	multiplier     float64
+	jitter         float64
+	retryIf        func(error) bool
+	attemptTimeout time.Duration
+}
+
+type RetryOption func(*retryOptions) error
+
+// RetryMaxAttempts sets how many times the function is attempted, including the first one.
+func RetryMaxAttempts(n int) RetryOption {
+	return func(o *retryOptions) error {
+		if n < 1 {
+			return fmt.Errorf("maxAttempts must be greater than 0")
+		}
+		o.maxAttempts = n
+		return nil
+	}
+}
+
+// RetryBackoff sets the time to wait after the first failed attempt and the maximum time to wait between two attempts.
+func RetryBackoff(initial, max time.Duration) RetryOption {
+	return func(o *retryOptions) error {
+		if initial < 0 {
+			return fmt.Errorf("initial backoff must be greater than or equal to 0")
+		}
+		if max < initial {
+			return fmt.Errorf("max backoff must be greater than or equal to the initial backoff")
+		}
+		o.initialBackoff = initial
+		o.maxBackoff = max
+		return nil
+	}
+}
+
+// RetryMultiplier sets the factor by which the backoff grows after each failed attempt.
+func RetryMultiplier(m float64) RetryOption {
+	return func(o *retryOptions) error {
+		if m < 1 {
+			return fmt.Errorf("multiplier must be greater than or equal to 1")
+		}
+		o.multiplier = m
+		return nil
+	}
+}
+
+// RetryJitter randomizes the backoff, reducing it by up to the given fraction, so that concurrent retries are spread out.
+func RetryJitter(fraction float64) RetryOption {
+	return func(o *retryOptions) error {
+		if fraction < 0 || fraction > 1 {
+			return fmt.Errorf("jitter must be between 0 and 1")
+		}
+		o.jitter = fraction
+		return nil
+	}
+}
+
+// RetryIf sets the function that decides whether an error is transient and so worth retrying. By default, every error is.
+func RetryIf(retryable func(error) bool) RetryOption {
+	return func(o *retryOptions) error {
+		if retryable == nil {
+			return fmt.Errorf("retryIf function must not be nil")
+		}
+		o.retryIf = retryable
+		return nil
+	}
+}
+
+// RetryAttemptTimeout sets the timeout of each attempt: the function receives a context that is done once it expires.
+func RetryAttemptTimeout(d time.Duration) RetryOption {
+	return func(o *retryOptions) error {
+		if d <= 0 {
+			return fmt.Errorf("attemptTimeout must be greater than 0")
+		}
+		o.attemptTimeout = d
+		return nil
+	}
+}
+
+func newDefaultRetryOptions() *retryOptions {
+	return &retryOptions{
+		maxAttempts:    3,
+		initialBackoff: 100 * time.Millisecond,
+		maxBackoff:     10 * time.Second,
+		multiplier:     2,
+		jitter:         0,
+		retryIf:        func(error) bool { return true },
+		attemptTimeout: 0,
+	}
+}
+
+func applyRetryOptions(opt []RetryOption) (*retryOptions, error) {
+	opts := newDefaultRetryOptions()
+	for _, o := range opt {
+		if err := o(opts); err != nil {
+			return opts, err
+		}
+	}
+	return opts, nil
+}
+
+func assertRetryOptions(opt []RetryOption) *retryOptions {
+	opts, err := applyRetryOptions(opt)
+	if err != nil {
+		panic(fmt.Errorf("invalid retry options: %v", err))
+	}
+	return opts
+}
@@ -0,0 +1,250 @@
+package rivo_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/agiac/rivo"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func ExampleRetry() {
+	ctx := context.Background()
+
+	// fetch fails the first two times it's called
+	var calls atomic.Int32
+	fetch := func(ctx context.Context, id int) (string, error) {
+		if calls.Add(1) < 3 {
+			return "", errors.New("service unavailable")
+		}
+		return fmt.Sprintf("record %d", id), nil
+	}
+
+	p := Pipe(Of(1), Map(Retry(fetch, RetryBackoff(time.Millisecond, 10*time.Millisecond))))
+
+	for s := range p(ctx, nil, nil) {
+		fmt.Println(s)
+	}
+
+	// Output:
+	// record 1
+}
+
+func TestRetry(t *testing.T) {
+	errTransient := errors.New("transient")
+	errPermanent := errors.New("permanent")
+
+	// failing returns a function that fails with the given errors, in order, and then succeeds
+	failing := func(errs ...error) (func(context.Context, int) (int, error), *atomic.Int32) {
+		var calls atomic.Int32
+		return func(ctx context.Context, n int) (int, error) {
+			if c := calls.Add(1); int(c) <= len(errs) {
+				return 0, errs[c-1]
+			}
+			return n * 2, nil
+		}, &calls
+	}
+
+	fast := RetryBackoff(time.Millisecond, time.Millisecond)
+
+	t.Run("succeed after retries", func(t *testing.T) {
+		f, calls := failing(errTransient, errTransient)
+
+		got, err := Retry(f, fast)(context.Background(), 1)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 2, got)
+		assert.Equal(t, int32(3), calls.Load())
+	})
+
+	t.Run("wrap all attempt errors", func(t *testing.T) {
+		e1, e2, e3 := errors.New("error 1"), errors.New("error 2"), errors.New("error 3")
+		f, calls := failing(e1, e2, e3)
+
+		_, err := Retry(f, fast)(context.Background(), 1)
+
+		var retryErr *RetryError
+		if assert.ErrorAs(t, err, &retryErr) {
+			assert.Equal(t, []error{e1, e2, e3}, retryErr.Errs)
+		}
+		assert.ErrorIs(t, err, e1)
+		assert.EqualError(t, err, "error 3 (after 3 attempts)")
+		assert.Equal(t, int32(3), calls.Load())
+	})
+
+	t.Run("with max attempts", func(t *testing.T) {
+		f, calls := failing(errTransient, errTransient, errTransient, errTransient)
+
+		got, err := Retry(f, fast, RetryMaxAttempts(5))(context.Background(), 1)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 2, got)
+		assert.Equal(t, int32(5), calls.Load())
+	})
+
+	t.Run("with retryable error classifier", func(t *testing.T) {
+		f, calls := failing(errTransient, errPermanent)
+
+		_, err := Retry(f, fast, RetryIf(func(err error) bool {
+			return errors.Is(err, errTransient)
+		}))(context.Background(), 1)
+
+		assert.ErrorIs(t, err, errPermanent)
+		assert.Equal(t, int32(2), calls.Load())
+	})
+
+	t.Run("with exponential backoff", func(t *testing.T) {
+		f, _ := failing(errTransient, errTransient, errTransient)
+
+		start := time.Now()
+
+		_, err := Retry(f, RetryMaxAttempts(4), RetryBackoff(10*time.Millisecond, 25*time.Millisecond))(context.Background(), 1)
+
+		// 10ms, 20ms and then 25ms instead of 40ms
+		assert.NoError(t, err)
+		elapsed := time.Since(start)
+		assert.GreaterOrEqual(t, elapsed, 55*time.Millisecond)
+		assert.Less(t, elapsed, 75*time.Millisecond+50*time.Millisecond)
+	})
+
+	t.Run("with jitter", func(t *testing.T) {
+		f, _ := failing(errTransient)
+
+		start := time.Now()
+
+		_, err := Retry(f, RetryBackoff(40*time.Millisecond, time.Second), RetryJitter(0.5))(context.Background(), 1)
+
+		assert.NoError(t, err)
+		assert.GreaterOrEqual(t, time.Since(start), 20*time.Millisecond)
+	})
+
+	t.Run("with attempt timeout", func(t *testing.T) {
+		var calls atomic.Int32
+		f := func(ctx context.Context, n int) (int, error) {
+			if calls.Add(1) == 1 {
+				<-ctx.Done()
+				return 0, ctx.Err()
+			}
+			return n, nil
+		}
+
+		got, err := Retry(f, fast, RetryAttemptTimeout(10*time.Millisecond))(context.Background(), 1)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 1, got)
+		assert.Equal(t, int32(2), calls.Load())
+	})
+
+	t.Run("with context cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+
+		f, calls := failing(errTransient, errTransient)
+
+		time.AfterFunc(10*time.Millisecond, cancel)
+
+		_, err := Retry(f, RetryBackoff(time.Hour, time.Hour))(ctx, 1)
+
+		assert.ErrorIs(t, err, errTransient)
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.Equal(t, int32(1), calls.Load())
+	})
+
+	t.Run("panics", func(t *testing.T) {
+		f, _ := failing()
+		assert.Panics(t, func() { Retry(f, RetryMaxAttempts(0)) })
+		assert.Panics(t, func() { Retry(f, RetryBackoff(time.Second, time.Millisecond)) })
+		assert.Panics(t, func() { Retry(f, RetryMultiplier(0.5)) })
+		assert.Panics(t, func() { Retry(f, RetryJitter(2)) })
+		assert.Panics(t, func() { Retry(f, RetryIf(nil)) })
+		assert.Panics(t, func() { Retry(f, RetryAttemptTimeout(0)) })
+	})
+}
+
+func TestRetryOptions(t *testing.T) {
+	fast := RetryBackoff(time.Millisecond, time.Millisecond)
+
+	// flaky returns a function that fails the first time it's called for each item
+	flaky := func() func(n int) error {
+		var failed [10]atomic.Bool
+		return func(n int) error {
+			if failed[n].CompareAndSwap(false, true) {
+				return fmt.Errorf("error on %d", n)
+			}
+			return nil
+		}
+	}
+
+	t.Run("map", func(t *testing.T) {
+		ctx := context.Background()
+		fail := flaky()
+
+		m := Map(func(ctx context.Context, n int) (int, error) {
+			return n * 2, fail(n)
+		}, MapRetry(fast))
+
+		got, err := RunCollect(ctx, Pipe(Of(1, 2, 3), m))
+
+		assert.NoError(t, err)
+		assert.Equal(t, []int{2, 4, 6}, got)
+	})
+
+	t.Run("map with final error", func(t *testing.T) {
+		ctx := context.Background()
+
+		m := Map(func(ctx context.Context, n int) (int, error) {
+			return 0, fmt.Errorf("error on %d", n)
+		}, MapRetry(fast, RetryMaxAttempts(2)))
+
+		got, err := RunCollect(ctx, Pipe(Of(1), m))
+
+		assert.Empty(t, got)
+		assert.EqualError(t, err, "error on 1 (after 2 attempts)")
+	})
+
+	t.Run("do", func(t *testing.T) {
+		ctx := context.Background()
+		fail := flaky()
+
+		var count atomic.Int32
+		d := Do(func(ctx context.Context, n int) error {
+			if err := fail(n); err != nil {
+				return err
+			}
+			count.Add(1)
+			return nil
+		}, DoRetry(fast))
+
+		err := Run(ctx, Pipe(Of(1, 2, 3), d))
+
+		assert.NoError(t, err)
+		assert.Equal(t, int32(3), count.Load())
+	})
+
+	t.Run("filter map", func(t *testing.T) {
+		ctx := context.Background()
+		fail := flaky()
+
+		fm := FilterMap(func(ctx context.Context, n int) (bool, int, error) {
+			return n%2 == 1, n, fail(n)
+		}, FilterMapRetry(fast))
+
+		got, err := RunCollect(ctx, Pipe(Of(1, 2, 3), fm))
+
+		assert.NoError(t, err)
+		assert.Equal(t, []int{1, 3}, got)
+	})
+
+	t.Run("panics", func(t *testing.T) {
+		assert.Panics(t, func() {
+			Map(func(ctx context.Context, n int) (int, error) { return n, nil }, MapRetry(RetryMaxAttempts(0)))
+		})
+		assert.Panics(t, func() {
+			Do(func(ctx context.Context, n int) error { return nil }, DoRetry(RetryMaxAttempts(0)))
+		})
+	})
+}
@@ -0,0 +1,98 @@
+package rivo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Run runs the given pipeline until its output stream is closed and returns the errors its stages sent to the error channel.
+// Run owns the error channel: it creates it, collects the errors in a separate goroutine, drains the output stream and
+// closes the channel only once every stage has stopped.
+// By default, all the errors are joined with errors.Join; use RunFirstError to return only the first one.
+// Use RunErrorPolicy to stop the pipeline early because of its errors.
+// Run panics if invalid options are provided.
+func Run(ctx context.Context, p Sync[None], opt ...RunOption) error {
+	_, err := RunCollect(ctx, p, opt...)
+	return err
+}
+
+// RunCollect is like Run, but it collects the items emitted by the generator and returns them as a slice.
+func RunCollect[T any](ctx context.Context, p Generator[T], opt ...RunOption) ([]T, error) {
+	o := mustRunOptions(opt)
+
+	if o.errorPolicy != nil {
+		p = WithErrorPolicy(*o.errorPolicy, p)
+	}
+
+	var collected []error
+
+	errs, wait := RunErrorSyncFunc(ctx, func(ctx context.Context, err error) {
+		if o.firstError && len(collected) > 0 {
+			return
+		}
+		collected = append(collected, err)
+	})
+
+	// The output stream is closed only after every stage has returned, so once it has been drained
+	// no stage can send to the error channel anymore and it's safe to close it.
+	items := Collect(p(ctx, nil, errs))
+
+	wait()
+
+	if o.firstError && len(collected) > 0 {
+		return items, collected[0]
+	}
+
+	return items, errors.Join(collected...)
+}
+
+type runOptions struct {
+	firstError  bool
+	errorPolicy *ErrorPolicy
+}
+
+type RunOption func(*runOptions) error
+
+// RunFirstError configures Run and RunCollect to return only the first error sent by the pipeline.
+// The following errors are still received, so that no stage is blocked, but they are discarded.
+func RunFirstError() RunOption {
+	return func(o *runOptions) error {
+		o.firstError = true
+		return nil
+	}
+}
+
+// RunErrorPolicy configures Run and RunCollect to stop the pipeline according to the given ErrorPolicy.
+// See WithErrorPolicy for details.
+func RunErrorPolicy(policy ErrorPolicy) RunOption {
+	return func(o *runOptions) error {
+		o.errorPolicy = &policy
+		return nil
+	}
+}
+
+func newDefaultRunOptions() *runOptions {
+	return &runOptions{
+		firstError:  false,
+		errorPolicy: nil,
+	}
+}
+
+func applyRunOptions(opts []RunOption) (*runOptions, error) {
+	o := newDefaultRunOptions()
+	for _, opt := range opts {
+		if err := opt(o); err != nil {
+			return nil, err
+		}
+	}
+	return o, nil
+}
+
+func mustRunOptions(opts []RunOption) *runOptions {
+	o, err := applyRunOptions(opts)
+	if err != nil {
+		panic(fmt.Sprintf("invalid RunOption: %v", err))
+	}
+	return o
+}
@@ -0,0 +1,141 @@
+package rivo_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"testing"
+
+	. "github.com/agiac/rivo"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func ExampleRun() {
+	ctx := context.Background()
+
+	g := Of("1", "2", "invalid", "4")
+
+	toInt := Map(func(ctx context.Context, s string) (int, error) {
+		return strconv.Atoi(s)
+	})
+
+	printInt := Do(func(ctx context.Context, n int) error {
+		fmt.Println(n)
+		return nil
+	})
+
+	err := Run(ctx, Pipe3(g, toInt, printInt))
+
+	fmt.Println("ERROR:", err)
+
+	// Output:
+	// 1
+	// 2
+	// 4
+	// ERROR: strconv.Atoi: parsing "invalid": invalid syntax
+}
+
+func TestRun(t *testing.T) {
+	t.Run("no errors", func(t *testing.T) {
+		ctx := context.Background()
+
+		count := 0
+		d := Do(func(ctx context.Context, n int) error {
+			count++
+			return nil
+		})
+
+		err := Run(ctx, Pipe(Of(1, 2, 3), d))
+
+		assert.NoError(t, err)
+		assert.Equal(t, 3, count)
+	})
+
+	t.Run("join all errors", func(t *testing.T) {
+		ctx := context.Background()
+
+		err1 := errors.New("error 1")
+		err2 := errors.New("error 2")
+
+		d := Do(func(ctx context.Context, n int) error {
+			switch n {
+			case 1:
+				return err1
+			case 3:
+				return err2
+			}
+			return nil
+		})
+
+		err := Run(ctx, Pipe(Of(1, 2, 3), d))
+
+		assert.ErrorIs(t, err, err1)
+		assert.ErrorIs(t, err, err2)
+	})
+
+	t.Run("first error only", func(t *testing.T) {
+		ctx := context.Background()
+
+		err1 := errors.New("error 1")
+		err2 := errors.New("error 2")
+
+		d := Do(func(ctx context.Context, n int) error {
+			switch n {
+			case 1:
+				return err1
+			case 3:
+				return err2
+			}
+			return nil
+		})
+
+		err := Run(ctx, Pipe(Of(1, 2, 3), d), RunFirstError())
+
+		assert.Equal(t, err1, err)
+	})
+
+	t.Run("errors from every stage", func(t *testing.T) {
+		ctx := context.Background()
+
+		toInt := Map(func(ctx context.Context, s string) (int, error) {
+			return strconv.Atoi(s)
+		})
+
+		d := Do(func(ctx context.Context, n int) error {
+			return fmt.Errorf("error on %d", n)
+		})
+
+		err := Run(ctx, Pipe3(Of("1", "a", "2"), toInt, d))
+
+		assert.ErrorContains(t, err, `parsing "a"`)
+		assert.ErrorContains(t, err, "error on 1")
+		assert.ErrorContains(t, err, "error on 2")
+	})
+}
+
+func TestRunCollect(t *testing.T) {
+	t.Run("collect items and errors", func(t *testing.T) {
+		ctx := context.Background()
+
+		toInt := Map(func(ctx context.Context, s string) (int, error) {
+			return strconv.Atoi(s)
+		})
+
+		got, err := RunCollect(ctx, Pipe(Of("1", "a", "2"), toInt))
+
+		assert.Equal(t, []int{1, 2}, got)
+		assert.ErrorContains(t, err, `parsing "a"`)
+	})
+
+	t.Run("context cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		got, err := RunCollect(ctx, Of(1, 2, 3, 4, 5))
+
+		assert.NoError(t, err)
+		assert.Less(t, len(got), 3)
+	})
+}
@@ -0,0 +1,63 @@
+package rivo
+
+import (
+	"context"
+	"time"
+)
+
+// Sample returns a pipeline that emits, every d, the latest item of the input stream received since the previous
+// tick, if any, discarding the others.
+// When the input stream is closed, the last item is emitted right away if it's still waiting.
+// Sample panics if d is not positive or if invalid options are provided.
+func Sample[T any](d time.Duration, opt ...TimeOption) Pipeline[T, T] {
+	if d <= 0 {
+		panic("d must be greater than 0")
+	}
+
+	o := assertTimeOptions(opt)
+
+	return func(ctx context.Context, in Stream[T], errs chan<- error) Stream[T] {
+		out := make(chan T, o.bufferSize)
+
+		if DescribeStage(ctx, TopologyNode{Kind: "Sample", BufferSize: o.bufferSize}, in, out) {
+			return out
+		}
+
+		go func() {
+			defer close(out)
+
+			ticker := o.clock.NewTicker(d)
+			defer ticker.Stop()
+
+			var last T
+			pending := false
+
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case v, ok := <-in:
+					if !ok {
+						if pending {
+							sendLatest(ctx, out, last)
+						}
+						return
+					}
+
+					last, pending = v, true
+				case <-ticker.C():
+					if !pending {
+						continue
+					}
+
+					if exit := sendLatest(ctx, out, last); exit {
+						return
+					}
+					pending = false
+				}
+			}
+		}()
+
+		return out
+	}
+}
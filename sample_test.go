@@ -0,0 +1,54 @@
+package rivo_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/agiac/rivo"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSample(t *testing.T) {
+	t.Run("emit the latest item on each tick", func(t *testing.T) {
+		ctx := context.Background()
+
+		clock := NewFakeClock(time.Now())
+
+		in := make(chan int)
+		out := Sample[int](time.Second, TimeClock(clock))(ctx, in, nil)
+
+		clock.BlockUntil(1)
+
+		in <- 1
+		in <- 2
+		clock.Advance(time.Second)
+
+		assert.Equal(t, 2, <-out)
+
+		// Nothing is emitted if no item is received between two ticks
+		clock.Advance(time.Second)
+
+		in <- 3
+		close(in)
+
+		assert.Equal(t, []int{3}, Collect(out))
+	})
+
+	t.Run("with context cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+
+		in := make(chan int)
+		out := Sample[int](time.Hour)(ctx, in, nil)
+
+		in <- 1
+		cancel()
+
+		assert.Empty(t, Collect(out))
+	})
+
+	t.Run("panics", func(t *testing.T) {
+		assert.Panics(t, func() { Sample[int](0) })
+	})
+}
@@ -0,0 +1,98 @@
+package rivo
+
+import (
+	"context"
+	"fmt"
+)
+
+// Scan returns a pipeline that accumulates the items of the input stream, starting from the initial value,
+// and emits the accumulated value after each item.
+// If the function returns an error, it's sent to the error channel and the item is skipped.
+func Scan[T, U any](initial U, f func(ctx context.Context, acc U, val T) (U, error), opt ...ScanOption) Pipeline[T, U] {
+	o := assertScanOptions(opt)
+
+	return func(ctx context.Context, in Stream[T], errs chan<- error) Stream[U] {
+		out := make(chan U, o.bufferSize)
+
+		if DescribeStage(ctx, TopologyNode{Kind: "Scan", BufferSize: o.bufferSize}, in, out) {
+			return out
+		}
+
+		go func() {
+			defer close(out)
+
+			acc := initial
+
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case v, ok := <-in:
+					if !ok {
+						return
+					}
+
+					next, err := f(ctx, acc, v)
+					if err != nil {
+						select {
+						case <-ctx.Done():
+							return
+						case errs <- err:
+						}
+						continue
+					}
+
+					acc = next
+
+					select {
+					case <-ctx.Done():
+						return
+					case out <- acc:
+					}
+				}
+			}
+		}()
+
+		return out
+	}
+}
+
+type scanOptions struct {
+	bufferSize int
+}
+
+type ScanOption func(*scanOptions) error
+
+func ScanBufferSize(n int) ScanOption {
+	return func(o *scanOptions) error {
+		if n < 0 {
+			return fmt.Errorf("bufferSize must be greater than or equal to 0")
+		}
+		o.bufferSize = n
+		return nil
+	}
+}
+
+func newDefaultScanOptions() *scanOptions {
+	return &scanOptions{
+		bufferSize: 0,
+	}
+}
+
+func applyScanOptions(opt []ScanOption) (*scanOptions, error) {
+	opts := newDefaultScanOptions()
+	for _, o := range opt {
+		if err := o(opts); err != nil {
+			return opts, err
+		}
+	}
+	return opts, nil
+}
+
+func assertScanOptions(opt []ScanOption) *scanOptions {
+	opts, err := applyScanOptions(opt)
+	if err != nil {
+		panic(fmt.Errorf("invalid scan options: %v", err))
+	}
+	return opts
+}
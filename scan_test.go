@@ -0,0 +1,75 @@
+package rivo_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	. "github.com/agiac/rivo"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func ExampleScan() {
+	ctx := context.Background()
+
+	in := Of(1, 2, 3, 4, 5)
+
+	runningSum := Scan(0, func(ctx context.Context, acc int, n int) (int, error) {
+		return acc + n, nil
+	})
+
+	for n := range Pipe(in, runningSum)(ctx, nil, nil) {
+		fmt.Println(n)
+	}
+
+	// Output:
+	// 1
+	// 3
+	// 6
+	// 10
+	// 15
+}
+
+func TestScan(t *testing.T) {
+	sum := func(ctx context.Context, acc int, n int) (int, error) {
+		return acc + n, nil
+	}
+
+	t.Run("skip items with errors", func(t *testing.T) {
+		ctx := context.Background()
+
+		s := Scan(0, func(ctx context.Context, acc int, n int) (int, error) {
+			if n == 2 {
+				return 0, errors.New("error on 2")
+			}
+			return acc + n, nil
+		})
+
+		got, err := RunCollect(ctx, Pipe(Of(1, 2, 3), s))
+
+		assert.Equal(t, []int{1, 4}, got)
+		assert.EqualError(t, err, "error on 2")
+	})
+
+	t.Run("context cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		got := Collect(Pipe(Of(1, 2, 3, 4, 5), Scan(0, sum))(ctx, nil, nil))
+
+		assert.Less(t, len(got), 3)
+	})
+
+	t.Run("with buffer size", func(t *testing.T) {
+		ctx := context.Background()
+
+		out := Pipe(Of(1, 2, 3), Scan(0, sum, ScanBufferSize(3)))(ctx, nil, nil)
+
+		got := Collect(out)
+
+		assert.Equal(t, 3, cap(out))
+		assert.Equal(t, []int{1, 3, 6}, got)
+	})
+}
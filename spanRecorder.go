@@ -0,0 +1,131 @@
+package rivo
+
+import (
+	"context"
+	"encoding/binary"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+)
+
+// SpanRecorder is a Tracer that keeps the spans in memory, so that they can be inspected with Spans, e.g. in tests.
+// The trace and span IDs are generated sequentially.
+type SpanRecorder struct {
+	mu     sync.Mutex
+	lastID uint64
+	spans  []*recordedSpan
+}
+
+// NewSpanRecorder returns an empty SpanRecorder.
+func NewSpanRecorder() *SpanRecorder {
+	return &SpanRecorder{}
+}
+
+// Start implements Tracer.
+func (r *SpanRecorder) Start(ctx context.Context, name string, links ...SpanContext) (context.Context, Span) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	parent := SpanContextFromContext(ctx)
+
+	r.lastID++
+
+	sc := SpanContext{TraceID: parent.TraceID}
+	binary.BigEndian.PutUint64(sc.SpanID[:], r.lastID)
+	if !parent.IsValid() {
+		binary.BigEndian.PutUint64(sc.TraceID[8:], r.lastID)
+	}
+
+	s := &recordedSpan{span: RecordedSpan{
+		Name:        name,
+		SpanContext: sc,
+		Parent:      parent,
+		Links:       slices.Clone(links),
+		Attributes:  make(map[string]any),
+		Start:       time.Now(),
+	}}
+	r.spans = append(r.spans, s)
+
+	return ContextWithSpanContext(ctx, sc), s
+}
+
+// Spans returns the spans started so far, in the order they were started.
+func (r *SpanRecorder) Spans() []RecordedSpan {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	spans := make([]RecordedSpan, 0, len(r.spans))
+	for _, s := range r.spans {
+		spans = append(spans, s.snapshot())
+	}
+
+	return spans
+}
+
+// RecordedSpan is a span recorded by a SpanRecorder.
+type RecordedSpan struct {
+	Name        string
+	SpanContext SpanContext
+	// Parent is the span context of the parent span, if any.
+	Parent     SpanContext
+	Links      []SpanContext
+	Attributes map[string]any
+	Start      time.Time
+	// End is the time the span ended, or the zero time if it hasn't ended yet.
+	End time.Time
+	// Err is the error the span ended with, if any.
+	Err error
+}
+
+// Ended reports whether the span has ended.
+func (s RecordedSpan) Ended() bool {
+	return !s.End.IsZero()
+}
+
+// Duration returns how long the span lasted, or 0 if it hasn't ended yet.
+func (s RecordedSpan) Duration() time.Duration {
+	if !s.Ended() {
+		return 0
+	}
+	return s.End.Sub(s.Start)
+}
+
+type recordedSpan struct {
+	mu   sync.Mutex
+	span RecordedSpan
+}
+
+func (s *recordedSpan) SpanContext() SpanContext {
+	return s.span.SpanContext
+}
+
+func (s *recordedSpan) SetAttribute(key string, value any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.span.Attributes[key] = value
+}
+
+func (s *recordedSpan) End(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.span.Ended() {
+		return
+	}
+
+	s.span.End = time.Now()
+	s.span.Err = err
+}
+
+func (s *recordedSpan) snapshot() RecordedSpan {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	span := s.span
+	span.Links = slices.Clone(span.Links)
+	span.Attributes = maps.Clone(span.Attributes)
+
+	return span
+}
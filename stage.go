@@ -0,0 +1,219 @@
+package rivo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// StageError is an error sent by a named stage. See Named.
+type StageError struct {
+	// Stage is the name of the stage.
+	Stage string
+	// Index is the position of the item in the input stream of the stage, or -1 if the error is not related to an item.
+	Index int
+	// Item is the item that was being processed, if any.
+	Item any
+	Err  error
+}
+
+func (e *StageError) Error() string {
+	if e.Index < 0 {
+		return fmt.Sprintf("%s: %v", e.Stage, e.Err)
+	}
+	return fmt.Sprintf("%s: item %d: %v", e.Stage, e.Index, e.Err)
+}
+
+func (e *StageError) Unwrap() error {
+	return e.Err
+}
+
+type stageNameKey struct{}
+
+// stageName returns the name of the stage set by Named, if any.
+func stageName(ctx context.Context) string {
+	name, _ := ctx.Value(stageNameKey{}).(string)
+	return name
+}
+
+// Named returns a pipeline that runs p as a stage with the given name: the errors it sends to the error channel are
+// wrapped in a StageError with that name. The operators based on ForEachOutput, such as Map, Filter or Do, add the
+// processed item and its index to the error. Errors that are already StageErrors, e.g. of a nested named stage,
+// are forwarded unchanged.
+// Named panics if the name is empty.
+func Named[T, U any](name string, p Pipeline[T, U]) Pipeline[T, U] {
+	if name == "" {
+		panic("name must not be empty")
+	}
+
+	return func(ctx context.Context, in Stream[T], errs chan<- error) Stream[U] {
+		ctx = context.WithValue(ctx, stageNameKey{}, name)
+
+		if errs == nil {
+			return p(ctx, in, nil)
+		}
+
+		return interceptErrors(ctx, func(pErrs chan<- error) Stream[U] {
+			return p(ctx, in, pErrs)
+		}, func(err error) {
+			var stageErr *StageError
+			if !errors.As(err, &stageErr) {
+				err = &StageError{Stage: name, Index: -1, Err: err}
+			}
+
+			select {
+			case <-ctx.Done():
+			case errs <- err:
+			}
+		}, nil)
+	}
+}
+
+// interceptErrors runs a pipeline with an error channel of its own, passing the errors it sends to handle, and returns
+// a stream forwarding its output, aliased to it with aliasStream. The errors are handled by a single goroutine.
+// Once the output of the pipeline is closed and its errors have been handled, onClose, if not nil, is called and the
+// output stream is closed. Like Run, this relies on the stages of the pipeline sending their errors before its output
+// is closed, which doesn't hold for stages whose outputs are not part of it, e.g. a branch of TeeStream which is not
+// merged back or the inputs of Merge once its context is cancelled: the errors they send afterwards are not handled
+// and they are blocked until their context is cancelled.
+func interceptErrors[U any](ctx context.Context, run func(errs chan<- error) Stream[U], handle func(error), onClose func()) Stream[U] {
+	out := make(chan U)
+
+	pErrs := make(chan error)
+	pDone := make(chan struct{})
+	errsDone := make(chan struct{})
+
+	go func() {
+		defer close(errsDone)
+
+		for {
+			select {
+			case err := <-pErrs:
+				handle(err)
+			case <-pDone:
+				return
+			}
+		}
+	}()
+
+	pOut := run(pErrs)
+	aliasStream(ctx, pOut, Stream[U](out))
+
+	go func() {
+		defer close(out)
+
+		for v := range pOut {
+			select {
+			case <-ctx.Done():
+			case out <- v:
+			}
+		}
+
+		// The error channel is never closed, since some stages might still be running
+		close(pDone)
+		<-errsDone
+
+		if onClose != nil {
+			onClose()
+		}
+	}()
+
+	return out
+}
+
+// stageErrors wraps the errors sent by a worker of a named stage, or of a stage with dead letters, so that they carry
+// the item being processed.
+// The errors go through a forwarding goroutine; flush waits until the errors of the current item have been forwarded,
+// so that they are not wrapped with the next item.
+// With a StageObserver, the errors are also counted, while with trackFailures the first error of each item is kept,
+// so that it can be recorded in the span of the item or in the error rate of the stage.
+type stageErrors struct {
+	name       string
+	itemErrors bool
+	obs        StageObserver
+	track      bool
+	errs       chan<- error
+	ch         chan error
+	done       chan struct{}
+	index      int
+	item       any
+	err        error
+}
+
+func newStageErrors(ctx context.Context, errs chan<- error, obs StageObserver, trackFailures bool) *stageErrors {
+	s := &stageErrors{name: stageName(ctx), itemErrors: withItemErrors(ctx), obs: obs, track: trackFailures, errs: errs}
+
+	if (s.name == "" && !s.itemErrors && obs == nil && !trackFailures) || errs == nil {
+		return s
+	}
+
+	s.ch = make(chan error)
+	s.done = make(chan struct{})
+
+	go func() {
+		defer close(s.done)
+
+		for err := range s.ch {
+			if err == nil {
+				continue
+			}
+
+			if obs != nil {
+				obs.Error()
+			}
+
+			if s.err == nil {
+				s.err = err
+			}
+
+			select {
+			case <-ctx.Done():
+			case errs <- s.wrap(err):
+			}
+		}
+	}()
+
+	return s
+}
+
+// wrap attaches the current item to the error: in a StageError if the stage is named, otherwise in an ItemError
+// if the failed items are required.
+func (s *stageErrors) wrap(err error) error {
+	switch {
+	case s.name != "":
+		return &StageError{Stage: s.name, Index: s.index, Item: s.item, Err: err}
+	case s.itemErrors:
+		return &ItemError{Item: s.item, Err: err}
+	default:
+		return err
+	}
+}
+
+// forItem returns the error channel to use while processing the item with the given index.
+func (s *stageErrors) forItem(index int, item any) chan<- error {
+	if s.ch == nil {
+		return s.errs
+	}
+
+	s.index, s.item, s.err = index, item, nil
+
+	return s.ch
+}
+
+// failure returns the first error sent for the current item, if any. It must be called after flush.
+func (s *stageErrors) failure() error {
+	return s.err
+}
+
+func (s *stageErrors) flush() {
+	if s.ch != nil {
+		s.ch <- nil
+	}
+}
+
+func (s *stageErrors) close() {
+	if s.ch != nil {
+		close(s.ch)
+		<-s.done
+	}
+}
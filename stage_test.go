@@ -0,0 +1,190 @@
+package rivo_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"testing"
+
+	. "github.com/agiac/rivo"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func ExampleNamed() {
+	ctx := context.Background()
+
+	parse := Named("parse", Map(func(ctx context.Context, s string) (int, error) {
+		return strconv.Atoi(s)
+	}))
+
+	double := Named("double", Map(func(ctx context.Context, n int) (int, error) {
+		return n * 2, nil
+	}))
+
+	got, err := RunCollect(ctx, Pipe3(Of("1", "two", "3"), parse, double))
+
+	fmt.Println(got)
+	fmt.Println(err)
+
+	var stageErr *StageError
+	if errors.As(err, &stageErr) {
+		fmt.Printf("stage %q, item %d: %q\n", stageErr.Stage, stageErr.Index, stageErr.Item)
+	}
+
+	// Output:
+	// [2 6]
+	// parse: item 1: strconv.Atoi: parsing "two": invalid syntax
+	// stage "parse", item 1: "two"
+}
+
+func TestNamed(t *testing.T) {
+	errOdd := errors.New("odd")
+
+	failOdd := func(ctx context.Context, n int) (int, error) {
+		if n%2 == 1 {
+			return 0, errOdd
+		}
+		return n, nil
+	}
+
+	t.Run("wrap errors with item and index", func(t *testing.T) {
+		ctx := context.Background()
+
+		got, err := RunCollect(ctx, Pipe(Of(1, 2, 3, 4), Named("even", Map(failOdd))))
+
+		assert.Equal(t, []int{2, 4}, got)
+		assert.EqualError(t, err, "even: item 0: odd\neven: item 2: odd")
+		assert.ErrorIs(t, err, errOdd)
+	})
+
+	t.Run("with pool size", func(t *testing.T) {
+		ctx := context.Background()
+
+		var errs []error
+		errCh, wait := RunErrorSyncFunc(ctx, func(ctx context.Context, err error) {
+			errs = append(errs, err)
+		})
+
+		Collect(Pipe(Of(1, 2, 3, 4, 5, 6, 7, 8), Named("even", Map(failOdd, MapPoolSize(4))))(ctx, nil, errCh))
+		wait()
+
+		// The index of each error matches its item
+		assert.Len(t, errs, 4)
+		for _, err := range errs {
+			var stageErr *StageError
+			if assert.ErrorAs(t, err, &stageErr) {
+				assert.Equal(t, "even", stageErr.Stage)
+				assert.Equal(t, stageErr.Index+1, stageErr.Item)
+			}
+		}
+	})
+
+	t.Run("with preserve order", func(t *testing.T) {
+		ctx := context.Background()
+
+		p := Named("even", Map(failOdd, MapPoolSize(2), MapPreserveOrder()))
+
+		got, err := RunCollect(ctx, Pipe(Of(1, 2, 3, 4), p))
+
+		assert.Equal(t, []int{2, 4}, got)
+
+		// The errors are sent by different workers, in any order
+		var messages []string
+		for _, err := range err.(interface{ Unwrap() []error }).Unwrap() {
+			messages = append(messages, err.Error())
+		}
+		assert.ElementsMatch(t, []string{"even: item 0: odd", "even: item 2: odd"}, messages)
+	})
+
+	t.Run("with errors not related to an item", func(t *testing.T) {
+		ctx := context.Background()
+
+		failed := false
+		g := FromFunc(func(ctx context.Context) (int, bool, error) {
+			if !failed {
+				failed = true
+				return 0, true, errors.New("unavailable")
+			}
+			return 0, false, nil
+		})
+
+		_, err := RunCollect(ctx, Named("source", g))
+
+		var stageErr *StageError
+		if assert.ErrorAs(t, err, &stageErr) {
+			assert.Equal(t, -1, stageErr.Index)
+			assert.Nil(t, stageErr.Item)
+		}
+		assert.EqualError(t, err, "source: FromFunc: unavailable")
+	})
+
+	t.Run("with nested stages", func(t *testing.T) {
+		ctx := context.Background()
+
+		p := Named("outer", Pipe(Named("inner", Map(failOdd)), Map(failOdd)))
+
+		_, err := RunCollect(ctx, Pipe(Of(1), p))
+
+		assert.EqualError(t, err, "inner: item 0: odd")
+	})
+
+	t.Run("with timeouts and panics", func(t *testing.T) {
+		ctx := context.Background()
+
+		m := Map(func(ctx context.Context, n int) (int, error) {
+			panic("boom")
+		}, MapRecover())
+
+		_, err := RunCollect(ctx, Pipe(Of(1), Named("panic", m)))
+
+		var stageErr *StageError
+		var panicErr *PanicError
+		assert.ErrorAs(t, err, &stageErr)
+		assert.ErrorAs(t, err, &panicErr)
+		assert.EqualError(t, err, "panic: item 0: panic: boom")
+	})
+
+	t.Run("with nil error channel", func(t *testing.T) {
+		ctx := context.Background()
+
+		got := Collect(Pipe(Of(2, 4), Named("even", Map(failOdd)))(ctx, nil, nil))
+
+		assert.Equal(t, []int{2, 4}, got)
+	})
+
+	t.Run("with errors sent after the output is closed", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+
+		// The second branch of the tee sends an error after the output is closed
+		late := make(chan struct{})
+		done := make(chan struct{})
+		p := func(ctx context.Context, in Stream[int], errs chan<- error) Stream[int] {
+			a, b := TeeStream(ctx, in)
+			go func() {
+				defer close(done)
+				for range b {
+				}
+				<-late
+				select {
+				case <-ctx.Done():
+				case errs <- errors.New("late"):
+				}
+			}()
+			return a
+		}
+
+		got := Collect(Named("tee", p)(ctx, Of(1, 2)(ctx, nil, nil), make(chan error)))
+		assert.Equal(t, []int{1, 2}, got)
+
+		// The late error is not handled, so the stage is blocked until the context is cancelled
+		close(late)
+		cancel()
+		<-done
+	})
+
+	t.Run("panics", func(t *testing.T) {
+		assert.Panics(t, func() { Named("", Map(failOdd)) })
+	})
+}
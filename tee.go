@@ -21,7 +21,28 @@ func TeeStreamN[T any](ctx context.Context, in Stream[T], n int) []Stream[T] {
 		out[i] = make(chan T)
 	}
 
+	streams := make([]Stream[T], n)
+	described := make([]any, n)
+	for i := 0; i < n; i++ {
+		streams[i] = out[i]
+		described[i] = streams[i]
+	}
+
+	if describeStage(ctx, TopologyNode{Kind: "Tee"}, []any{in}, described) {
+		go func() {
+			defer func() {
+				for i := 0; i < n; i++ {
+					close(out[i])
+				}
+			}()
+			drain(in)
+		}()
+		return streams
+	}
+
 	go func() {
+		labelGoroutine(ctx, "Tee", -1)
+
 		defer func() {
 			for i := 0; i < n; i++ {
 				close(out[i])
@@ -35,11 +56,6 @@ func TeeStreamN[T any](ctx context.Context, in Stream[T], n int) []Stream[T] {
 		}
 	}()
 
-	streams := make([]Stream[T], n)
-	for i := 0; i < n; i++ {
-		streams[i] = out[i]
-	}
-
 	return streams
 }
 
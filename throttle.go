@@ -0,0 +1,111 @@
+package rivo
+
+import (
+	"context"
+	"time"
+)
+
+// ThrottleFirst returns a pipeline that emits the first item of the input stream and then discards the following
+// ones until d has passed, after which the next item is emitted and so on.
+// ThrottleFirst panics if d is not positive or if invalid options are provided.
+func ThrottleFirst[T any](d time.Duration, opt ...TimeOption) Pipeline[T, T] {
+	if d <= 0 {
+		panic("d must be greater than 0")
+	}
+
+	o := assertTimeOptions(opt)
+
+	return func(ctx context.Context, in Stream[T], errs chan<- error) Stream[T] {
+		out := make(chan T, o.bufferSize)
+
+		if DescribeStage(ctx, TopologyNode{Kind: "ThrottleFirst", BufferSize: o.bufferSize}, in, out) {
+			return out
+		}
+
+		go func() {
+			defer close(out)
+
+			var next time.Time
+
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case v, ok := <-in:
+					if !ok {
+						return
+					}
+
+					now := o.clock.Now()
+					if now.Before(next) {
+						continue
+					}
+					next = now.Add(d)
+
+					if exit := sendLatest(ctx, out, v); exit {
+						return
+					}
+				}
+			}
+		}()
+
+		return out
+	}
+}
+
+// ThrottleLast returns a pipeline that, once it receives an item of the input stream, waits for d and then emits
+// the latest item received meanwhile, discarding the others.
+// When the input stream is closed, the last item is emitted right away if it's still waiting.
+// ThrottleLast panics if d is not positive or if invalid options are provided.
+func ThrottleLast[T any](d time.Duration, opt ...TimeOption) Pipeline[T, T] {
+	if d <= 0 {
+		panic("d must be greater than 0")
+	}
+
+	o := assertTimeOptions(opt)
+
+	return func(ctx context.Context, in Stream[T], errs chan<- error) Stream[T] {
+		out := make(chan T, o.bufferSize)
+
+		if DescribeStage(ctx, TopologyNode{Kind: "ThrottleLast", BufferSize: o.bufferSize}, in, out) {
+			return out
+		}
+
+		go func() {
+			defer close(out)
+
+			timer := o.clock.NewTimer(d)
+			timer.Stop()
+			defer timer.Stop()
+
+			var last T
+			pending := false
+
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case v, ok := <-in:
+					if !ok {
+						if pending {
+							sendLatest(ctx, out, last)
+						}
+						return
+					}
+
+					if !pending {
+						timer.Reset(d)
+					}
+					last, pending = v, true
+				case <-timer.C():
+					if exit := sendLatest(ctx, out, last); exit {
+						return
+					}
+					pending = false
+				}
+			}
+		}()
+
+		return out
+	}
+}
@@ -0,0 +1,87 @@
+package rivo_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/agiac/rivo"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestThrottleFirst(t *testing.T) {
+	t.Run("emit the first item of each period", func(t *testing.T) {
+		ctx := context.Background()
+
+		clock := NewFakeClock(time.Now())
+
+		in := make(chan int)
+		out := ThrottleFirst[int](time.Second, TimeClock(clock), TimeBufferSize(10))(ctx, in, nil)
+
+		in <- 1
+		in <- 2
+		clock.Advance(500 * time.Millisecond)
+		in <- 3
+		clock.Advance(500 * time.Millisecond)
+		in <- 4
+		in <- 5
+		clock.Advance(2 * time.Second)
+		in <- 6
+		close(in)
+
+		assert.Equal(t, []int{1, 4, 6}, Collect(out))
+	})
+
+	t.Run("with context cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		in := make(chan int)
+
+		assert.Empty(t, Collect(ThrottleFirst[int](time.Second)(ctx, in, nil)))
+	})
+}
+
+func TestThrottleLast(t *testing.T) {
+	t.Run("emit the last item of each period", func(t *testing.T) {
+		ctx := context.Background()
+
+		in := make(chan int)
+
+		go func() {
+			defer close(in)
+			in <- 1
+			in <- 2
+			time.Sleep(150 * time.Millisecond)
+			in <- 3
+			time.Sleep(150 * time.Millisecond)
+		}()
+
+		got := Collect(ThrottleLast[int](50*time.Millisecond)(ctx, in, nil))
+
+		assert.Equal(t, []int{2, 3}, got)
+	})
+
+	t.Run("emit the waiting item when the input is closed", func(t *testing.T) {
+		ctx := context.Background()
+
+		clock := NewFakeClock(time.Now())
+
+		got := Collect(Pipe(Of(1, 2, 3), ThrottleLast[int](time.Second, TimeClock(clock)))(ctx, nil, nil))
+
+		assert.Equal(t, []int{3}, got)
+	})
+
+	t.Run("with context cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+
+		in := make(chan int)
+		out := ThrottleLast[int](time.Hour)(ctx, in, nil)
+
+		in <- 1
+		cancel()
+
+		assert.Empty(t, Collect(out))
+	})
+}
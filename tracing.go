@@ -0,0 +1,346 @@
+package rivo
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// SpanContext identifies a span. It has the same shape as the SpanContext of OpenTelemetry, so that they can be
+// converted to each other, e.g. with trace.NewSpanContext(trace.SpanContextConfig{TraceID: sc.TraceID, SpanID: sc.SpanID}).
+type SpanContext struct {
+	TraceID [16]byte
+	SpanID  [8]byte
+}
+
+// IsValid reports whether the span context identifies a span.
+func (sc SpanContext) IsValid() bool {
+	return sc != SpanContext{}
+}
+
+// Span is a span started by a Tracer.
+type Span interface {
+	SpanContext() SpanContext
+	SetAttribute(key string, value any)
+	// End ends the span, recording the error if not nil.
+	End(err error)
+}
+
+// Tracer starts the spans of the traced stages. See WithTracer.
+//
+// The interface is a subset of the OpenTelemetry tracing API, so that an adapter is only a few lines long:
+//
+//	type otelTracer struct{ tracer trace.Tracer }
+//
+//	func (t otelTracer) Start(ctx context.Context, name string, links ...rivo.SpanContext) (context.Context, rivo.Span) {
+//		if parent := rivo.SpanContextFromContext(ctx); parent.IsValid() {
+//			ctx = trace.ContextWithRemoteSpanContext(ctx, otelSpanContext(parent))
+//		}
+//		var opts []trace.SpanStartOption
+//		for _, l := range links {
+//			opts = append(opts, trace.WithLinks(trace.Link{SpanContext: otelSpanContext(l)}))
+//		}
+//		ctx, span := t.tracer.Start(ctx, name, opts...)
+//		return ctx, otelSpan{span}
+//	}
+//
+// where otelSpanContext converts a rivo.SpanContext with trace.NewSpanContext and otelSpan implements Span by
+// calling SetAttributes, RecordError, SetStatus and End on the OpenTelemetry span.
+type Tracer interface {
+	// Start starts a span with the given name, child of the span whose context is in ctx, if any, and linked to the
+	// given span contexts. The returned context is passed to the function processing the item.
+	Start(ctx context.Context, name string, links ...SpanContext) (context.Context, Span)
+}
+
+type spanContextKey struct{}
+
+// ContextWithSpanContext returns a copy of ctx carrying the given span context, as the parent of the spans started
+// with it.
+func ContextWithSpanContext(ctx context.Context, sc SpanContext) context.Context {
+	return context.WithValue(ctx, spanContextKey{}, sc)
+}
+
+// SpanContextFromContext returns the span context carried by ctx, if any.
+func SpanContextFromContext(ctx context.Context) SpanContext {
+	sc, _ := ctx.Value(spanContextKey{}).(SpanContext)
+	return sc
+}
+
+type tracingKey struct{}
+
+// tracing is the tracing state of a run of a pipeline.
+type tracing struct {
+	tracer  Tracer
+	mu      sync.Mutex
+	streams map[any]*tracedStream
+	aliases map[any]any
+}
+
+type tracingScopeKey struct{}
+
+// tracingScope holds the output streams of the traced stages started while wiring a pipeline. See wireTraced.
+type tracingScope struct {
+	streams []*tracedStream
+}
+
+// WithTracer returns a pipeline that runs p tracing its items with the given tracer.
+// The stages based on ForEachOutput, such as Map, Filter or Do, start a span for each item, named after the stage,
+// or its kind if it's not named, which ends with the first error sent for the item, if any. Batch starts a span for
+// each batch, linked to the spans of its items. The spans are children of the span of the item they come from, so that
+// each item carries its trace context across the stages, or otherwise of the span in the context of the pipeline, if
+// any: see ContextWithSpanContext. The context passed to the function processing an item carries its span.
+// The trace context is not propagated through the stages that are not traced, such as Merge or custom stages.
+func WithTracer[T, U any](tracer Tracer, p Pipeline[T, U]) Pipeline[T, U] {
+	return func(ctx context.Context, in Stream[T], errs chan<- error) Stream[U] {
+		t := &tracing{tracer: tracer, streams: make(map[any]*tracedStream), aliases: make(map[any]any)}
+
+		return wireTraced(context.WithValue(ctx, tracingKey{}, t), func(ctx context.Context) Stream[U] {
+			return p(ctx, in, errs)
+		})
+	}
+}
+
+// wireTraced wires a pipeline by calling wire and then drops the span contexts of the output streams of the traced
+// stages it started which are not read by a traced stage, so that they don't pile up. Since the stages can start
+// sending as soon as they are wired, whether a stream is read by a traced stage can only be decided once the whole
+// pipeline is wired: the operators wiring pipelines lazily, such as GroupBy, must wire them with wireTraced too.
+func wireTraced[U any](ctx context.Context, wire func(ctx context.Context) Stream[U]) Stream[U] {
+	t, ok := ctx.Value(tracingKey{}).(*tracing)
+	if !ok {
+		return wire(ctx)
+	}
+
+	scope := &tracingScope{}
+
+	out := wire(context.WithValue(ctx, tracingScopeKey{}, scope))
+
+	t.mu.Lock()
+	streams := scope.streams
+	t.mu.Unlock()
+
+	for _, s := range streams {
+		s.dropUnlessConsumed()
+	}
+
+	return out
+}
+
+// tracedStream queues the span contexts of the items of a stream, in the order the items are sent, so that the
+// receiving stage can pop the span context of each item it receives.
+// The span contexts are queued from the start, since the receiving stage is wired after the sending one, and they are
+// dropped once the pipeline is wired if the stream isn't read by a traced stage, so that they don't pile up.
+type tracedStream struct {
+	// sendMu serializes the senders, so that the span contexts are queued in the same order as the items
+	sendMu   sync.Mutex
+	mu       sync.Mutex
+	consumed bool
+	dropped  bool
+	spans    []SpanContext
+}
+
+func (s *tracedStream) consume() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.consumed = true
+}
+
+func (s *tracedStream) dropUnlessConsumed() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.consumed {
+		s.dropped = true
+		s.spans = nil
+	}
+}
+
+func (s *tracedStream) push(sc SpanContext) (queued bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.dropped {
+		return false
+	}
+
+	s.spans = append(s.spans, sc)
+
+	return true
+}
+
+// unpush removes the span context pushed last, for an item that was not sent.
+func (s *tracedStream) unpush(queued bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// The span contexts might have been dropped in the meantime
+	if queued && len(s.spans) > 0 {
+		s.spans = s.spans[:len(s.spans)-1]
+	}
+}
+
+func (s *tracedStream) pop() SpanContext {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.spans) == 0 {
+		return SpanContext{}
+	}
+
+	sc := s.spans[0]
+	s.spans = s.spans[1:]
+
+	return sc
+}
+
+// tracedSend sends an item to the output stream together with its span context, reporting it to the StageObserver,
+// if any, and returns false if the context is done first.
+func tracedSend[T any](ctx context.Context, obs StageObserver, s *tracedStream, out chan<- T, v T, sc SpanContext) bool {
+	s.sendMu.Lock()
+	defer s.sendMu.Unlock()
+
+	queued := s.push(sc)
+
+	start := time.Now()
+
+	select {
+	case <-ctx.Done():
+		s.unpush(queued)
+		return false
+	case out <- v:
+		if obs != nil {
+			obs.ItemSent(time.Since(start), len(out), cap(out))
+		}
+		return true
+	}
+}
+
+// stageTracer starts the spans of a run of a traced stage.
+type stageTracer struct {
+	tracer Tracer
+	name   string
+	kind   string
+	in     *tracedStream
+	out    *tracedStream
+}
+
+// traceStage returns the stageTracer for a run of a stage of the given kind with the given input and output streams,
+// or nil if the pipeline is not traced.
+func traceStage[T, U any](ctx context.Context, kind string, in Stream[T], out chan U) *stageTracer {
+	t, ok := ctx.Value(tracingKey{}).(*tracing)
+	if !ok {
+		return nil
+	}
+
+	name := stageName(ctx)
+	if name == "" {
+		name = kind
+	}
+
+	st := &stageTracer{tracer: t.tracer, name: name, kind: kind, out: &tracedStream{}}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.streams[Stream[U](out)] = st.out
+
+	if scope, ok := ctx.Value(tracingScopeKey{}).(*tracingScope); ok {
+		scope.streams = append(scope.streams, st.out)
+	}
+
+	if in != nil {
+		var s any = in
+		for {
+			from, ok := t.aliases[s]
+			if !ok {
+				break
+			}
+			s = from
+		}
+		if st.in = t.streams[s]; st.in != nil {
+			st.in.consume()
+		}
+	}
+
+	return st
+}
+
+// traceAlias records that the stream to forwards the items of the stream from, in the same order, if the pipeline is traced.
+func traceAlias(ctx context.Context, from, to any) {
+	t, ok := ctx.Value(tracingKey{}).(*tracing)
+	if !ok {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.aliases[to] = from
+}
+
+// parent returns the span context of the item just received from the input stream, if any. It must be called once
+// for each item, in the order they are received.
+func (t *stageTracer) parent() SpanContext {
+	if t.in == nil {
+		return SpanContext{}
+	}
+	return t.in.pop()
+}
+
+// start starts a span for an item with the given parent, or the span in ctx if the parent is not valid, and returns
+// a context carrying it.
+func (t *stageTracer) start(ctx context.Context, parent SpanContext, links ...SpanContext) (context.Context, Span) {
+	if parent.IsValid() {
+		ctx = ContextWithSpanContext(ctx, parent)
+	}
+
+	ctx, span := t.tracer.Start(ctx, t.name, links...)
+	span.SetAttribute("rivo.stage.kind", t.kind)
+
+	return ContextWithSpanContext(ctx, span.SpanContext()), span
+}
+
+// tracedOutput is the output stream of a worker of a traced stage: the outputs are sent to the output stream of the
+// stage with the span context of the item being processed, which is set with setSpan.
+type tracedOutput[U any] struct {
+	items chan U
+	spans chan SpanContext
+	done  chan struct{}
+}
+
+func newTracedOutput[U any](ctx context.Context, t *stageTracer, obs StageObserver, out chan<- U) *tracedOutput[U] {
+	o := &tracedOutput[U]{
+		items: make(chan U),
+		spans: make(chan SpanContext),
+		done:  make(chan struct{}),
+	}
+
+	go func() {
+		defer close(o.done)
+
+		// The worker sets the span and sends the outputs one at a time, so they are received in the same order
+		var sc SpanContext
+
+		for {
+			select {
+			case sc = <-o.spans:
+			case v, ok := <-o.items:
+				if !ok {
+					return
+				}
+				tracedSend(ctx, obs, t.out, out, v, sc)
+			}
+		}
+	}()
+
+	return o
+}
+
+func (o *tracedOutput[U]) setSpan(sc SpanContext) {
+	o.spans <- sc
+}
+
+func (o *tracedOutput[U]) close() {
+	close(o.items)
+	<-o.done
+}
@@ -0,0 +1,369 @@
+package rivo_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/agiac/rivo"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func ExampleWithTracer() {
+	ctx := context.Background()
+
+	tracer := NewSpanRecorder()
+
+	double := Named("double", Map(func(ctx context.Context, n int) (int, error) {
+		return n * 2, nil
+	}))
+
+	print := Named("print", Do(func(ctx context.Context, n int) error {
+		fmt.Println(n)
+		return nil
+	}))
+
+	_ = Run(ctx, WithTracer(tracer, Pipe3(Of(1, 2), double, print)))
+
+	names := make(map[SpanContext]string)
+	for _, s := range tracer.Spans() {
+		names[s.SpanContext] = s.Name
+	}
+
+	for _, s := range tracer.Spans() {
+		if s.Parent.IsValid() {
+			fmt.Printf("%s (item %d) is a child of %s\n", s.Name, s.Attributes["rivo.item.index"], names[s.Parent])
+		}
+	}
+
+	// Output:
+	// 2
+	// 4
+	// print (item 0) is a child of double
+	// print (item 1) is a child of double
+}
+
+// spansByContext indexes the recorded spans by their span context.
+func spansByContext(spans []RecordedSpan) map[SpanContext]RecordedSpan {
+	m := make(map[SpanContext]RecordedSpan, len(spans))
+	for _, s := range spans {
+		m[s.SpanContext] = s
+	}
+	return m
+}
+
+// itemSpans records the span context of the context passed to a stage for each item.
+type itemSpans struct {
+	mu    sync.Mutex
+	spans map[int]SpanContext
+}
+
+func (s *itemSpans) record(ctx context.Context, n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.spans == nil {
+		s.spans = make(map[int]SpanContext)
+	}
+	s.spans[n] = SpanContextFromContext(ctx)
+}
+
+func TestWithTracer(t *testing.T) {
+	t.Run("propagate the trace context of each item", func(t *testing.T) {
+		for _, preserveOrder := range []bool{false, true} {
+			t.Run(fmt.Sprintf("preserve order %v", preserveOrder), func(t *testing.T) {
+				ctx := context.Background()
+
+				tracer := NewSpanRecorder()
+
+				var doubled, printed itemSpans
+
+				opt := []MapOption{MapPoolSize(3)}
+				if preserveOrder {
+					opt = append(opt, MapPreserveOrder())
+				}
+
+				double := Named("double", Map(func(ctx context.Context, n int) (int, error) {
+					doubled.record(ctx, n)
+					return n * 2, nil
+				}, opt...))
+
+				sink := Do(func(ctx context.Context, n int) error {
+					printed.record(ctx, n)
+					return nil
+				}, DoPoolSize(2))
+
+				err := Run(ctx, WithTracer(tracer, Pipe3(Of(1, 2, 3, 4, 5, 6), double, sink)))
+				assert.NoError(t, err)
+
+				spans := spansByContext(tracer.Spans())
+				assert.Len(t, spans, 12)
+
+				for n := 1; n <= 6; n++ {
+					d := spans[doubled.spans[n]]
+					assert.Equal(t, "double", d.Name)
+					assert.Equal(t, "Map", d.Attributes["rivo.stage.kind"])
+					assert.Equal(t, n-1, d.Attributes["rivo.item.index"])
+					assert.False(t, d.Parent.IsValid())
+					assert.True(t, d.Ended())
+					assert.NoError(t, d.Err)
+
+					p := spans[printed.spans[n*2]]
+					assert.Equal(t, "Do", p.Name)
+					assert.Equal(t, "Do", p.Attributes["rivo.stage.kind"])
+					assert.Equal(t, d.SpanContext, p.Parent)
+					assert.Equal(t, d.SpanContext.TraceID, p.SpanContext.TraceID)
+					assert.True(t, p.Ended())
+				}
+			})
+		}
+	})
+
+	t.Run("record the first error of each item", func(t *testing.T) {
+		ctx := context.Background()
+
+		tracer := NewSpanRecorder()
+
+		errThree := errors.New("three")
+
+		even := Filter(func(ctx context.Context, n int) (bool, error) {
+			if n == 3 {
+				return false, errThree
+			}
+			return n%2 == 0, nil
+		})
+
+		got, err := RunCollect(ctx, WithTracer(tracer, Pipe(Of(1, 2, 3, 4), even)))
+		assert.Equal(t, []int{2, 4}, got)
+		assert.ErrorIs(t, err, errThree)
+
+		spans := tracer.Spans()
+		assert.Len(t, spans, 4)
+
+		for _, s := range spans {
+			assert.Equal(t, "Filter", s.Name)
+			if s.Attributes["rivo.item.index"] == 2 {
+				assert.ErrorIs(t, s.Err, errThree)
+			} else {
+				assert.NoError(t, s.Err)
+			}
+		}
+	})
+
+	t.Run("link the spans of the items of a batch", func(t *testing.T) {
+		ctx := context.Background()
+
+		tracer := NewSpanRecorder()
+
+		var mapped itemSpans
+		var sunk []SpanContext
+
+		identity := Map(func(ctx context.Context, n int) (int, error) {
+			mapped.record(ctx, n)
+			return n, nil
+		})
+
+		sink := Do(func(ctx context.Context, batch []int) error {
+			sunk = append(sunk, SpanContextFromContext(ctx))
+			return nil
+		})
+
+		err := Run(ctx, WithTracer(tracer, Pipe4(Of(1, 2, 3, 4, 5), identity, Batch[int](2), sink)))
+		assert.NoError(t, err)
+
+		spans := spansByContext(tracer.Spans())
+
+		var batches []RecordedSpan
+		for _, s := range tracer.Spans() {
+			if s.Name == "Batch" {
+				batches = append(batches, s)
+			}
+		}
+
+		assert.Len(t, batches, 3)
+		assert.Equal(t, []SpanContext{mapped.spans[1], mapped.spans[2]}, batches[0].Links)
+		assert.Equal(t, []SpanContext{mapped.spans[3], mapped.spans[4]}, batches[1].Links)
+		assert.Equal(t, []SpanContext{mapped.spans[5]}, batches[2].Links)
+
+		assert.Equal(t, 2, batches[0].Attributes["rivo.batch.size"])
+		assert.Equal(t, 1, batches[2].Attributes["rivo.batch.size"])
+
+		assert.Len(t, sunk, 3)
+		for i, sc := range sunk {
+			assert.Equal(t, batches[i].SpanContext, spans[sc].Parent)
+		}
+	})
+
+	t.Run("start the spans from the span of the context", func(t *testing.T) {
+		tracer := NewSpanRecorder()
+
+		rootCtx, root := tracer.Start(context.Background(), "root")
+
+		identity := Map(func(ctx context.Context, n int) (int, error) {
+			return n, nil
+		})
+
+		_, err := RunCollect(rootCtx, WithTracer(tracer, Pipe(Of(1, 2), identity)))
+		assert.NoError(t, err)
+
+		root.End(nil)
+
+		spans := tracer.Spans()
+		assert.Len(t, spans, 3)
+
+		for _, s := range spans[1:] {
+			assert.Equal(t, root.SpanContext(), s.Parent)
+			assert.Equal(t, root.SpanContext().TraceID, s.SpanContext.TraceID)
+		}
+	})
+
+	t.Run("start new traces after a stage that is not traced", func(t *testing.T) {
+		ctx := context.Background()
+
+		tracer := NewSpanRecorder()
+
+		identity := Map(func(ctx context.Context, n int) (int, error) {
+			return n, nil
+		}, MapBufferSize(3))
+
+		passThrough := func(ctx context.Context, in Stream[int], errs chan<- error) Stream[int] {
+			out := make(chan int)
+			go func() {
+				defer close(out)
+				for v := range in {
+					out <- v
+				}
+			}()
+			return out
+		}
+
+		got, err := RunCollect(ctx, WithTracer(tracer, Pipe4(Of(1, 2, 3), identity, passThrough, identity)))
+		assert.NoError(t, err)
+		assert.Equal(t, []int{1, 2, 3}, got)
+
+		spans := tracer.Spans()
+		assert.Len(t, spans, 6)
+
+		for _, s := range spans {
+			assert.False(t, s.Parent.IsValid())
+		}
+	})
+
+	t.Run("propagate the trace context to a stage wired late", func(t *testing.T) {
+		ctx := context.Background()
+
+		tracer := NewSpanRecorder()
+
+		var mapped, printed itemSpans
+
+		identity := Map(func(ctx context.Context, n int) (int, error) {
+			mapped.record(ctx, n)
+			return n, nil
+		}, MapBufferSize(3))
+
+		sink := Do(func(ctx context.Context, n int) error {
+			printed.record(ctx, n)
+			return nil
+		})
+
+		// The first stage sends its items before the second one is wired
+		p := func(ctx context.Context, in Stream[None], errs chan<- error) Stream[None] {
+			out := Pipe(Of(1, 2, 3), identity)(ctx, in, errs)
+			time.Sleep(20 * time.Millisecond)
+			return sink(ctx, out, errs)
+		}
+
+		err := Run(ctx, WithTracer(tracer, p))
+		assert.NoError(t, err)
+
+		spans := spansByContext(tracer.Spans())
+
+		for n := 1; n <= 3; n++ {
+			assert.Equal(t, mapped.spans[n], spans[printed.spans[n]].Parent)
+		}
+	})
+
+	t.Run("trace the groups of GroupBy", func(t *testing.T) {
+		ctx := context.Background()
+
+		tracer := NewSpanRecorder()
+
+		var mapped, printed itemSpans
+
+		group := func(key bool) Pipeline[int, int] {
+			identity := Map(func(ctx context.Context, n int) (int, error) {
+				mapped.record(ctx, n)
+				return n, nil
+			})
+
+			sink := Map(func(ctx context.Context, n int) (int, error) {
+				printed.record(ctx, n)
+				return n, nil
+			})
+
+			return Pipe(identity, sink)
+		}
+
+		odd := func(n int) bool { return n%2 == 1 }
+
+		got, err := RunCollect(ctx, WithTracer(tracer, Pipe(Of(1, 2, 3, 4), GroupBy(odd, group))))
+		assert.NoError(t, err)
+		assert.Len(t, got, 4)
+
+		spans := spansByContext(tracer.Spans())
+
+		for n := 1; n <= 4; n++ {
+			assert.Equal(t, mapped.spans[n], spans[printed.spans[n]].Parent)
+		}
+	})
+
+	t.Run("not traced", func(t *testing.T) {
+		ctx := context.Background()
+
+		var spans itemSpans
+
+		identity := Map(func(ctx context.Context, n int) (int, error) {
+			spans.record(ctx, n)
+			return n, nil
+		})
+
+		got, err := RunCollect(ctx, Pipe(Of(1, 2), identity))
+		assert.NoError(t, err)
+		assert.Equal(t, []int{1, 2}, got)
+		assert.False(t, spans.spans[1].IsValid())
+		assert.False(t, spans.spans[2].IsValid())
+	})
+}
+
+func TestSpanRecorder(t *testing.T) {
+	r := NewSpanRecorder()
+
+	ctx, parent := r.Start(context.Background(), "parent")
+	_, child := r.Start(ctx, "child", SpanContext{SpanID: [8]byte{1}})
+
+	child.SetAttribute("key", "value")
+	child.End(errors.New("failed"))
+	parent.End(nil)
+
+	spans := r.Spans()
+	assert.Len(t, spans, 2)
+
+	assert.Equal(t, "parent", spans[0].Name)
+	assert.True(t, spans[0].SpanContext.IsValid())
+	assert.False(t, spans[0].Parent.IsValid())
+	assert.True(t, spans[0].Ended())
+	assert.NoError(t, spans[0].Err)
+
+	assert.Equal(t, "child", spans[1].Name)
+	assert.Equal(t, spans[0].SpanContext, spans[1].Parent)
+	assert.Equal(t, spans[0].SpanContext.TraceID, spans[1].SpanContext.TraceID)
+	assert.NotEqual(t, spans[0].SpanContext.SpanID, spans[1].SpanContext.SpanID)
+	assert.Equal(t, []SpanContext{{SpanID: [8]byte{1}}}, spans[1].Links)
+	assert.Equal(t, map[string]any{"key": "value"}, spans[1].Attributes)
+	assert.EqualError(t, spans[1].Err, "failed")
+	assert.Equal(t, spans[1].End.Sub(spans[1].Start), spans[1].Duration())
+}
@@ -0,0 +1,109 @@
+package window
+
+import (
+	"context"
+	"time"
+
+	"github.com/agiac/rivo"
+)
+
+// TumblingCount returns a pipeline that groups the items of the input stream in non-overlapping windows of n items.
+// The last window, emitted when the input stream is closed, can have fewer items.
+// Start and End are the timestamps of the first and the last item of the window.
+// TumblingCount panics if n is not positive or if invalid options are provided.
+func TumblingCount[T any](n int, opt ...Option[T]) rivo.Pipeline[T, Window[T]] {
+	if n <= 0 {
+		panic("n must be greater than 0")
+	}
+
+	return slidingCount("window.TumblingCount", n, n, opt)
+}
+
+// SlidingCount returns a pipeline that emits a window with the last size items of the input stream every hop items.
+// The first windows can have fewer than size items, as well as the last one, which is emitted when the input stream is
+// closed if any item arrived after the previous window: it contains those items and the ones it would share with the
+// previous window if it was complete.
+// Start and End are the timestamps of the first and the last item of the window.
+// Count windows have no watermark, so the watermark options have no effect.
+// SlidingCount panics if size or hop are not positive or if invalid options are provided.
+func SlidingCount[T any](size, hop int, opt ...Option[T]) rivo.Pipeline[T, Window[T]] {
+	if size <= 0 {
+		panic("size must be greater than 0")
+	}
+
+	if hop <= 0 {
+		panic("hop must be greater than 0")
+	}
+
+	return slidingCount("window.SlidingCount", size, hop, opt)
+}
+
+func slidingCount[T any](kind string, size, hop int, opt []Option[T]) rivo.Pipeline[T, Window[T]] {
+	o := assertOptions(opt)
+
+	timestamp := func(item T) time.Time {
+		if o.eventTime != nil {
+			return o.eventTime(item)
+		}
+		return time.Now()
+	}
+
+	return func(ctx context.Context, in rivo.Stream[T], errs chan<- error) rivo.Stream[Window[T]] {
+		out := make(chan Window[T], o.bufferSize)
+
+		if rivo.DescribeStage(ctx, rivo.TopologyNode{Kind: kind, BufferSize: o.bufferSize}, in, out) {
+			return out
+		}
+
+		go func() {
+			defer close(out)
+			defer o.closeLate()
+
+			items := make([]T, 0, size)
+			timestamps := make([]time.Time, 0, size)
+			sinceLast := 0
+
+			send := func(n int) (exit bool) {
+				first := len(items) - min(n, len(items))
+
+				win := Window[T]{
+					Start: timestamps[first],
+					End:   timestamps[len(timestamps)-1],
+					Items: append([]T(nil), items[first:]...),
+				}
+
+				sinceLast = 0
+
+				select {
+				case <-ctx.Done():
+					return true
+				case out <- win:
+					return false
+				}
+			}
+
+			for item := range rivo.OrDone(ctx, in) {
+				if len(items) == size {
+					items = append(items[:0], items[1:]...)
+					timestamps = append(timestamps[:0], timestamps[1:]...)
+				}
+
+				items = append(items, item)
+				timestamps = append(timestamps, timestamp(item))
+				sinceLast++
+
+				if sinceLast == hop {
+					if exit := send(size); exit {
+						return
+					}
+				}
+			}
+
+			if sinceLast > 0 && ctx.Err() == nil {
+				send(sinceLast + max(size-hop, 0))
+			}
+		}()
+
+		return out
+	}
+}
@@ -0,0 +1,87 @@
+package window_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/agiac/rivo"
+	"github.com/agiac/rivo/window"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func ExampleSlidingCount() {
+	ctx := context.Background()
+
+	in := rivo.Of(1, 2, 3, 4, 5)
+
+	for win := range rivo.Pipe(in, window.SlidingCount[int](3, 2))(ctx, nil, nil) {
+		fmt.Println(win.Items)
+	}
+
+	// Output:
+	// [1 2]
+	// [2 3 4]
+	// [4 5]
+}
+
+func TestTumblingCount(t *testing.T) {
+	t.Run("group items by count", func(t *testing.T) {
+		ctx := context.Background()
+
+		got := rivo.Collect(rivo.Pipe(rivo.Of(1, 2, 3, 4, 5), window.TumblingCount[int](2))(ctx, nil, nil))
+
+		if assert.Len(t, got, 3) {
+			assert.Equal(t, []int{1, 2}, got[0].Items)
+			assert.Equal(t, []int{3, 4}, got[1].Items)
+			assert.Equal(t, []int{5}, got[2].Items)
+		}
+	})
+
+	t.Run("event time", func(t *testing.T) {
+		ctx := context.Background()
+
+		in := rivo.Of(at("a", 1), at("b", 2), at("c", 3))
+
+		got := rivo.Collect(rivo.Pipe(in, window.TumblingCount(3, window.EventTime(eventTime)))(ctx, nil, nil))
+
+		if assert.Len(t, got, 1) {
+			assert.Equal(t, t0.Add(1e9), got[0].Start)
+			assert.Equal(t, t0.Add(3e9), got[0].End)
+		}
+	})
+
+	t.Run("context cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		in := make(chan int)
+
+		got := rivo.Collect(window.TumblingCount[int](2)(ctx, in, nil))
+
+		assert.Empty(t, got)
+	})
+
+	t.Run("invalid options", func(t *testing.T) {
+		assert.Panics(t, func() { window.TumblingCount[int](0) })
+	})
+}
+
+func TestSlidingCount(t *testing.T) {
+	t.Run("hop greater than size", func(t *testing.T) {
+		ctx := context.Background()
+
+		got := rivo.Collect(rivo.Pipe(rivo.Of(1, 2, 3, 4, 5, 6), window.SlidingCount[int](2, 3))(ctx, nil, nil))
+
+		if assert.Len(t, got, 2) {
+			assert.Equal(t, []int{2, 3}, got[0].Items)
+			assert.Equal(t, []int{5, 6}, got[1].Items)
+		}
+	})
+
+	t.Run("invalid options", func(t *testing.T) {
+		assert.Panics(t, func() { window.SlidingCount[int](0, 1) })
+		assert.Panics(t, func() { window.SlidingCount[int](1, 0) })
+	})
+}
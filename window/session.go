@@ -0,0 +1,22 @@
+package window
+
+import (
+	"time"
+
+	"github.com/agiac/rivo"
+)
+
+// Session returns a pipeline that groups the items of the input stream in session windows, which are closed after a gap
+// of inactivity. Each window starts with the timestamp of its first item and ends gap after the timestamp of its last item.
+// Session panics if gap is not positive or if invalid options are provided.
+func Session[T any](gap time.Duration, opt ...Option[T]) rivo.Pipeline[T, Window[T]] {
+	if gap <= 0 {
+		panic("gap must be greater than 0")
+	}
+
+	o := assertOptions(opt)
+
+	return timeWindowPipeline("window.Session", func(ts time.Time) []span {
+		return []span{{start: ts, end: ts.Add(gap)}}
+	}, true, o)
+}
@@ -0,0 +1,33 @@
+package window
+
+import (
+	"time"
+
+	"github.com/agiac/rivo"
+)
+
+// Sliding returns a pipeline that groups the items of the input stream in windows of the given size, starting every hop.
+// If hop is less than size, the windows overlap and an item can belong to more than one window, while if hop is
+// greater than size, the items falling in the gaps between the windows are discarded.
+// Windows are aligned as with time.Time.Truncate, i.e. to the zero time, which matches the Unix epoch only if hop
+// divides 24 hours, and only windows with at least one item are emitted.
+// Sliding panics if size or hop are not positive or if invalid options are provided.
+func Sliding[T any](size, hop time.Duration, opt ...Option[T]) rivo.Pipeline[T, Window[T]] {
+	if size <= 0 {
+		panic("size must be greater than 0")
+	}
+
+	if hop <= 0 {
+		panic("hop must be greater than 0")
+	}
+
+	o := assertOptions(opt)
+
+	return timeWindowPipeline("window.Sliding", func(ts time.Time) []span {
+		var spans []span
+		for start := ts.Truncate(hop); start.Add(size).After(ts); start = start.Add(-hop) {
+			spans = append(spans, span{start: start, end: start.Add(size)})
+		}
+		return spans
+	}, false, o)
+}
@@ -0,0 +1,24 @@
+package window
+
+import (
+	"time"
+
+	"github.com/agiac/rivo"
+)
+
+// Tumbling returns a pipeline that groups the items of the input stream in fixed-size, non-overlapping windows of the given duration.
+// Windows are aligned as with time.Time.Truncate, i.e. to the zero time, which matches the Unix epoch only if size
+// divides 24 hours, and only windows with at least one item are emitted.
+// Tumbling panics if size is not positive or if invalid options are provided.
+func Tumbling[T any](size time.Duration, opt ...Option[T]) rivo.Pipeline[T, Window[T]] {
+	if size <= 0 {
+		panic("size must be greater than 0")
+	}
+
+	o := assertOptions(opt)
+
+	return timeWindowPipeline("window.Tumbling", func(ts time.Time) []span {
+		start := ts.Truncate(size)
+		return []span{{start: start, end: start.Add(size)}}
+	}, false, o)
+}
@@ -0,0 +1,210 @@
+package window_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/agiac/rivo"
+	"github.com/agiac/rivo/window"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func ExampleLateItems() {
+	ctx := context.Background()
+
+	in := rivo.Of(at("a", 1), at("b", 12), at("c", 8), at("d", 17), at("late", 3))
+
+	late, lateOpt := window.LateItems[event]()
+
+	w := window.Tumbling(10*time.Second,
+		window.EventTime(eventTime),
+		window.MaxOutOfOrderness[event](5*time.Second),
+		lateOpt,
+	)
+
+	out := rivo.Pipe(in, w)(ctx, nil, nil)
+
+	lateItems := make(chan []event)
+	go func() {
+		lateItems <- rivo.Collect(late)
+	}()
+
+	for win := range out {
+		fmt.Println(win.Start.Format(time.TimeOnly), names(win))
+	}
+
+	for _, e := range <-lateItems {
+		fmt.Println("late:", e.name)
+	}
+
+	// Output:
+	// 00:00:00 [a c]
+	// 00:00:10 [b d]
+	// late: late
+}
+
+func TestWatermark(t *testing.T) {
+	t.Run("max out of orderness delays the windows", func(t *testing.T) {
+		ctx := context.Background()
+
+		// c is out of order, but within the max out of orderness
+		in := rivo.Of(at("a", 1), at("b", 12), at("c", 8), at("d", 16))
+
+		w := window.Tumbling(10*time.Second, window.EventTime(eventTime), window.MaxOutOfOrderness[event](5*time.Second))
+
+		got := rivo.Collect(rivo.Pipe(in, w)(ctx, nil, nil))
+
+		if assert.Len(t, got, 2) {
+			assert.Equal(t, []string{"a", "c"}, names(got[0]))
+			assert.Equal(t, []string{"b", "d"}, names(got[1]))
+		}
+	})
+
+	t.Run("windows fire when the watermark passes their end", func(t *testing.T) {
+		ctx := context.Background()
+
+		in := make(chan event)
+
+		w := window.Tumbling(10*time.Second, window.EventTime(eventTime), window.MaxOutOfOrderness[event](5*time.Second))
+
+		out := w(ctx, in, nil)
+
+		in <- at("a", 1)
+		in <- at("b", 14)
+
+		select {
+		case <-out:
+			assert.Fail(t, "the window should not fire before the watermark passes its end")
+		case <-time.After(20 * time.Millisecond):
+		}
+
+		in <- at("c", 15)
+
+		first := <-out
+		assert.Equal(t, []string{"a"}, names(first))
+
+		close(in)
+
+		rest := rivo.Collect(out)
+		if assert.Len(t, rest, 1) {
+			assert.Equal(t, []string{"b", "c"}, names(rest[0]))
+		}
+	})
+
+	t.Run("allowed lateness emits updated windows", func(t *testing.T) {
+		ctx := context.Background()
+
+		in := rivo.Of(at("a", 1), at("b", 12), at("late", 5), at("c", 25), at("too late", 6))
+
+		late, lateOpt := window.LateItems[event]()
+
+		w := window.Tumbling(10*time.Second,
+			window.EventTime(eventTime),
+			window.AllowedLateness[event](10*time.Second),
+			lateOpt,
+		)
+
+		out := rivo.Pipe(in, w)(ctx, nil, nil)
+
+		var lateItems []event
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			lateItems = rivo.Collect(late)
+		}()
+
+		got := rivo.Collect(out)
+		<-done
+
+		if assert.Len(t, got, 4) {
+			assert.Equal(t, []string{"a"}, names(got[0]))
+			assert.Equal(t, []string{"a", "late"}, names(got[1]))
+			assert.Equal(t, t0, got[1].Start)
+			assert.Equal(t, []string{"b"}, names(got[2]))
+			assert.Equal(t, []string{"c"}, names(got[3]))
+		}
+
+		assert.Equal(t, []event{at("too late", 6)}, lateItems)
+	})
+
+	t.Run("periodic watermark", func(t *testing.T) {
+		ctx := context.Background()
+
+		in := make(chan event)
+
+		w := window.Tumbling(10*time.Second, window.EventTime(eventTime), window.WatermarkInterval[event](20*time.Millisecond))
+
+		out := w(ctx, in, nil)
+
+		in <- at("a", 1)
+		in <- at("b", 11)
+
+		// The window is emitted on the next watermark tick
+		first := <-out
+		assert.Equal(t, []string{"a"}, names(first))
+
+		close(in)
+
+		rest := rivo.Collect(out)
+		if assert.Len(t, rest, 1) {
+			assert.Equal(t, []string{"b"}, names(rest[0]))
+		}
+	})
+
+	t.Run("late items of session windows", func(t *testing.T) {
+		ctx := context.Background()
+
+		in := rivo.Of(at("a", 0), at("b", 20), at("late", 1))
+
+		late, lateOpt := window.LateItems[event]()
+
+		w := window.Session(5*time.Second, window.EventTime(eventTime), lateOpt)
+
+		out := rivo.Pipe(in, w)(ctx, nil, nil)
+
+		lateItems := make(chan []event)
+		go func() {
+			lateItems <- rivo.Collect(late)
+		}()
+
+		got := rivo.Collect(out)
+
+		assert.Len(t, got, 2)
+		assert.Equal(t, []event{at("late", 1)}, <-lateItems)
+	})
+
+	t.Run("watermark options require event time", func(t *testing.T) {
+		assert.Panics(t, func() { window.Tumbling(time.Second, window.AllowedLateness[int](time.Second)) })
+		assert.Panics(t, func() { window.Tumbling(time.Second, window.MaxOutOfOrderness[int](time.Second)) })
+		assert.Panics(t, func() { window.Tumbling(time.Second, window.WatermarkInterval[int](time.Second)) })
+
+		_, lateOpt := window.LateItems[int]()
+		assert.Panics(t, func() { window.Tumbling(time.Second, lateOpt) })
+	})
+
+	t.Run("invalid options", func(t *testing.T) {
+		eventTime := window.EventTime(func(n int) time.Time { return time.Unix(int64(n), 0) })
+
+		assert.Panics(t, func() { window.Tumbling(time.Second, eventTime, window.AllowedLateness[int](-1)) })
+		assert.Panics(t, func() { window.Tumbling(time.Second, eventTime, window.MaxOutOfOrderness[int](-1)) })
+		assert.Panics(t, func() { window.Tumbling(time.Second, eventTime, window.WatermarkInterval[int](0)) })
+	})
+}
+
+func TestLateItemsClosed(t *testing.T) {
+	ctx := context.Background()
+
+	late, lateOpt := window.LateItems[event]()
+
+	w := window.Tumbling(10*time.Second, window.EventTime(eventTime), lateOpt)
+
+	got := rivo.Collect(rivo.Pipe(rivo.Of(at("a", 1)), w)(ctx, nil, nil))
+
+	assert.Len(t, got, 1)
+
+	_, ok := <-late
+	assert.False(t, ok, "late items stream should be closed")
+}
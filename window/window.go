@@ -0,0 +1,425 @@
+package window
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/agiac/rivo"
+)
+
+// Window is a group of items of a stream. For time windows, the timestamps of the items fall in [Start, End).
+type Window[T any] struct {
+	Start time.Time
+	End   time.Time
+	Items []T
+}
+
+// span is the time interval of a window.
+type span struct {
+	start time.Time
+	end   time.Time
+}
+
+// assigner returns the spans of the windows an item with the given timestamp belongs to.
+type assigner func(ts time.Time) []span
+
+// pane is a window of a time window pipeline, together with its state.
+// A pane is fired when it's emitted for the first time and it's pending when it has items that were not emitted yet.
+type pane[T any] struct {
+	Window[T]
+	fired   bool
+	pending bool
+}
+
+// timeWindows holds the panes of a time window pipeline.
+// If merge is true, overlapping windows are merged together, as is the case for session windows.
+// Fired panes are retained until the watermark passes their end plus the allowed lateness, so that late items
+// can still be added to them.
+type timeWindows[T any] struct {
+	assign   assigner
+	merge    bool
+	lateness time.Duration
+	panes    []*pane[T]
+}
+
+// add adds the item to the windows it belongs to, skipping those that have already been purged.
+// It returns false if the item belongs to windows that have all been purged, i.e. if it's late. An item that doesn't
+// belong to any window, as in the gaps between sliding windows, is not late.
+func (w *timeWindows[T]) add(item T, ts time.Time, watermark time.Time) bool {
+	spans := w.assign(ts)
+	if len(spans) == 0 {
+		return true
+	}
+
+	added := false
+
+	for _, s := range spans {
+		if !s.end.Add(w.lateness).After(watermark) && !w.overlaps(s) {
+			continue
+		}
+
+		added = true
+
+		if i := slices.IndexFunc(w.panes, func(p *pane[T]) bool {
+			return p.Start.Equal(s.start) && p.End.Equal(s.end)
+		}); i >= 0 {
+			w.panes[i].Items = append(w.panes[i].Items, item)
+			w.panes[i].pending = true
+			continue
+		}
+
+		w.panes = append(w.panes, &pane[T]{
+			Window:  Window[T]{Start: s.start, End: s.end, Items: []T{item}},
+			pending: true,
+		})
+
+		if w.merge {
+			w.mergeOverlapping()
+		}
+	}
+
+	return added
+}
+
+func (w *timeWindows[T]) overlaps(s span) bool {
+	if !w.merge {
+		return false
+	}
+
+	return slices.ContainsFunc(w.panes, func(p *pane[T]) bool {
+		return p.Start.Before(s.end) && s.start.Before(p.End)
+	})
+}
+
+func (w *timeWindows[T]) mergeOverlapping() {
+	slices.SortFunc(w.panes, func(a, b *pane[T]) int {
+		return a.Start.Compare(b.Start)
+	})
+
+	merged := w.panes[:1]
+	for _, p := range w.panes[1:] {
+		last := merged[len(merged)-1]
+		if p.Start.Before(last.End) {
+			if p.End.After(last.End) {
+				last.End = p.End
+			}
+			last.Items = append(last.Items, p.Items...)
+			last.fired = last.fired || p.fired
+			last.pending = last.pending || p.pending
+			continue
+		}
+		merged = append(merged, p)
+	}
+
+	w.panes = merged
+}
+
+// fire returns the pending windows that end before or at the watermark, in order of end time, and purges the
+// windows whose allowed lateness has passed.
+func (w *timeWindows[T]) fire(watermark time.Time) []Window[T] {
+	var fired []Window[T]
+
+	retained := w.panes[:0]
+	for _, p := range w.panes {
+		if p.pending && !p.End.After(watermark) {
+			fired = append(fired, Window[T]{Start: p.Start, End: p.End, Items: slices.Clone(p.Items)})
+			p.fired = true
+			p.pending = false
+		}
+
+		if p.End.Add(w.lateness).After(watermark) {
+			retained = append(retained, p)
+		}
+	}
+	w.panes = retained
+
+	slices.SortStableFunc(fired, func(a, b Window[T]) int {
+		if c := a.End.Compare(b.End); c != 0 {
+			return c
+		}
+		return a.Start.Compare(b.Start)
+	})
+
+	return fired
+}
+
+// fireAll returns all the pending windows, in order of end time, and purges every window.
+func (w *timeWindows[T]) fireAll() []Window[T] {
+	var latest time.Time
+	for _, p := range w.panes {
+		if end := p.End.Add(w.lateness); end.After(latest) {
+			latest = end
+		}
+	}
+	return w.fire(latest)
+}
+
+// nextEnd returns the earliest end time of the windows that have not been fired yet.
+func (w *timeWindows[T]) nextEnd() (time.Time, bool) {
+	var next time.Time
+	found := false
+
+	for _, p := range w.panes {
+		if p.fired {
+			continue
+		}
+		if !found || p.End.Before(next) {
+			next = p.End
+			found = true
+		}
+	}
+
+	return next, found
+}
+
+// timeWindowPipeline returns a pipeline that groups the items of the input stream in the windows returned by the assigner.
+// With processing time, each item is timestamped on arrival and the windows are emitted as soon as their end time passes.
+// With event time, the windows are emitted as soon as the watermark passes their end. The watermark is the latest
+// timestamp seen so far minus the maximum out-of-orderness, and it's advanced on every item or periodically.
+// Late items are added to the windows whose allowed lateness has not passed yet, which are then emitted again, or
+// otherwise sent to the late items stream, if any, or discarded.
+// In both cases, the remaining windows are emitted when the input stream is closed.
+func timeWindowPipeline[T any](kind string, assign assigner, merge bool, o *options[T]) rivo.Pipeline[T, Window[T]] {
+	return func(ctx context.Context, in rivo.Stream[T], errs chan<- error) rivo.Stream[Window[T]] {
+		out := make(chan Window[T], o.bufferSize)
+
+		if rivo.DescribeStage(ctx, rivo.TopologyNode{Kind: kind, BufferSize: o.bufferSize}, in, out) {
+			return out
+		}
+
+		go func() {
+			defer close(out)
+			defer o.closeLate()
+
+			w := &timeWindows[T]{assign: assign, merge: merge, lateness: o.allowedLateness}
+
+			send := func(windows []Window[T]) (exit bool) {
+				for _, win := range windows {
+					select {
+					case <-ctx.Done():
+						return true
+					case out <- win:
+					}
+				}
+				return false
+			}
+
+			sendLate := func(item T) (exit bool) {
+				if o.late == nil {
+					return false
+				}
+
+				select {
+				case <-ctx.Done():
+					return true
+				case o.late <- item:
+					return false
+				}
+			}
+
+			var maxTimestamp, watermark time.Time
+
+			advanceWatermark := func() (exit bool) {
+				if wm := maxTimestamp.Add(-o.maxOutOfOrderness); wm.After(watermark) {
+					watermark = wm
+				}
+				return send(w.fire(watermark))
+			}
+
+			timer := time.NewTimer(time.Hour)
+			timer.Stop()
+			defer timer.Stop()
+
+			resetTimer := func() {
+				timer.Stop()
+				if o.eventTime != nil {
+					return
+				}
+				if next, ok := w.nextEnd(); ok {
+					timer.Reset(time.Until(next))
+				}
+			}
+
+			var watermarkTick <-chan time.Time
+			if o.eventTime != nil && o.watermarkInterval > 0 {
+				ticker := time.NewTicker(o.watermarkInterval)
+				defer ticker.Stop()
+				watermarkTick = ticker.C
+			}
+
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case item, ok := <-in:
+					if !ok {
+						send(w.fireAll())
+						return
+					}
+
+					if o.eventTime == nil {
+						w.add(item, time.Now(), time.Time{})
+						resetTimer()
+						continue
+					}
+
+					ts := o.eventTime(item)
+
+					if !w.add(item, ts, watermark) {
+						if exit := sendLate(item); exit {
+							return
+						}
+					}
+
+					if ts.After(maxTimestamp) {
+						maxTimestamp = ts
+					}
+
+					if watermarkTick == nil {
+						if exit := advanceWatermark(); exit {
+							return
+						}
+					}
+				case <-watermarkTick:
+					if exit := advanceWatermark(); exit {
+						return
+					}
+				case now := <-timer.C:
+					if exit := send(w.fire(now)); exit {
+						return
+					}
+					resetTimer()
+				}
+			}
+		}()
+
+		return out
+	}
+}
+
+type options[T any] struct {
+	eventTime         func(T) time.Time
+	bufferSize        int
+	maxOutOfOrderness time.Duration
+	allowedLateness   time.Duration
+	watermarkInterval time.Duration
+	late              chan T
+	closeLate         func()
+}
+
+// Option configures a window pipeline.
+type Option[T any] func(*options[T]) error
+
+// EventTime configures a window pipeline to use the timestamp returned by the given function for each item,
+// instead of its arrival time.
+func EventTime[T any](ts func(T) time.Time) Option[T] {
+	return func(o *options[T]) error {
+		if ts == nil {
+			return fmt.Errorf("eventTime function must not be nil")
+		}
+		o.eventTime = ts
+		return nil
+	}
+}
+
+// BufferSize sets the buffer size of the output stream of a window pipeline.
+func BufferSize[T any](n int) Option[T] {
+	return func(o *options[T]) error {
+		if n < 0 {
+			return fmt.Errorf("bufferSize must be greater than or equal to 0")
+		}
+		o.bufferSize = n
+		return nil
+	}
+}
+
+// MaxOutOfOrderness sets how much the timestamps of the items can be out of order: the watermark of an event time
+// window pipeline is the latest timestamp seen so far minus d. It requires EventTime.
+func MaxOutOfOrderness[T any](d time.Duration) Option[T] {
+	return func(o *options[T]) error {
+		if d < 0 {
+			return fmt.Errorf("maxOutOfOrderness must be greater than or equal to 0")
+		}
+		o.maxOutOfOrderness = d
+		return nil
+	}
+}
+
+// AllowedLateness sets for how long after the watermark has passed their end the windows of an event time window
+// pipeline are retained. Late items belonging to a retained window are added to it and the window is emitted again
+// with all its items. It requires EventTime.
+func AllowedLateness[T any](d time.Duration) Option[T] {
+	return func(o *options[T]) error {
+		if d < 0 {
+			return fmt.Errorf("allowedLateness must be greater than or equal to 0")
+		}
+		o.allowedLateness = d
+		return nil
+	}
+}
+
+// WatermarkInterval configures an event time window pipeline to advance the watermark periodically, every d,
+// instead of on every item. It requires EventTime.
+func WatermarkInterval[T any](d time.Duration) Option[T] {
+	return func(o *options[T]) error {
+		if d <= 0 {
+			return fmt.Errorf("watermarkInterval must be greater than 0")
+		}
+		o.watermarkInterval = d
+		return nil
+	}
+}
+
+// LateItems returns a stream and an option that configures an event time window pipeline to send to that stream
+// the items that arrive too late to be added to any window, instead of discarding them.
+// The stream must be consumed concurrently with the output of the pipeline, otherwise the pipeline is blocked,
+// and it's closed when the output of the pipeline is closed. The option must be used with a single pipeline.
+// It requires EventTime.
+func LateItems[T any]() (rivo.Stream[T], Option[T]) {
+	late := make(chan T)
+	closeLate := sync.OnceFunc(func() { close(late) })
+
+	return late, func(o *options[T]) error {
+		o.late = late
+		o.closeLate = closeLate
+		return nil
+	}
+}
+
+func newDefaultOptions[T any]() *options[T] {
+	return &options[T]{
+		eventTime:         nil,
+		bufferSize:        0,
+		maxOutOfOrderness: 0,
+		allowedLateness:   0,
+		watermarkInterval: 0,
+		late:              nil,
+		closeLate:         func() {},
+	}
+}
+
+func applyOptions[T any](opt []Option[T]) (*options[T], error) {
+	opts := newDefaultOptions[T]()
+	for _, o := range opt {
+		if err := o(opts); err != nil {
+			return opts, err
+		}
+	}
+
+	if opts.eventTime == nil && (opts.maxOutOfOrderness > 0 || opts.allowedLateness > 0 || opts.watermarkInterval > 0 || opts.late != nil) {
+		return opts, fmt.Errorf("watermark options require EventTime")
+	}
+
+	return opts, nil
+}
+
+func assertOptions[T any](opt []Option[T]) *options[T] {
+	opts, err := applyOptions(opt)
+	if err != nil {
+		panic(fmt.Errorf("invalid window options: %v", err))
+	}
+	return opts
+}
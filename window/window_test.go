@@ -0,0 +1,282 @@
+package window_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/agiac/rivo"
+	"github.com/agiac/rivo/window"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type event struct {
+	name string
+	at   time.Time
+}
+
+var t0 = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func at(name string, seconds int) event {
+	return event{name: name, at: t0.Add(time.Duration(seconds) * time.Second)}
+}
+
+func eventTime(e event) time.Time {
+	return e.at
+}
+
+func names(w window.Window[event]) []string {
+	var nn []string
+	for _, e := range w.Items {
+		nn = append(nn, e.name)
+	}
+	return nn
+}
+
+func ExampleTumbling() {
+	ctx := context.Background()
+
+	in := rivo.Of(at("a", 1), at("b", 4), at("c", 11), at("d", 25))
+
+	w := window.Tumbling(10*time.Second, window.EventTime(eventTime))
+
+	for win := range rivo.Pipe(in, w)(ctx, nil, nil) {
+		fmt.Println(win.Start.Format(time.TimeOnly), win.End.Format(time.TimeOnly), names(win))
+	}
+
+	// Output:
+	// 00:00:00 00:00:10 [a b]
+	// 00:00:10 00:00:20 [c]
+	// 00:00:20 00:00:30 [d]
+}
+
+func TestTumbling(t *testing.T) {
+	t.Run("event time", func(t *testing.T) {
+		ctx := context.Background()
+
+		in := rivo.Of(at("a", 0), at("b", 9), at("c", 10), at("d", 35), at("e", 39))
+
+		got := rivo.Collect(rivo.Pipe(in, window.Tumbling(10*time.Second, window.EventTime(eventTime)))(ctx, nil, nil))
+
+		if assert.Len(t, got, 3) {
+			assert.Equal(t, []string{"a", "b"}, names(got[0]))
+			assert.Equal(t, t0, got[0].Start)
+			assert.Equal(t, t0.Add(10*time.Second), got[0].End)
+			assert.Equal(t, []string{"c"}, names(got[1]))
+			assert.Equal(t, []string{"d", "e"}, names(got[2]))
+			assert.Equal(t, t0.Add(30*time.Second), got[2].Start)
+		}
+	})
+
+	t.Run("event time discards items of emitted windows", func(t *testing.T) {
+		ctx := context.Background()
+
+		in := rivo.Of(at("a", 1), at("b", 12), at("late", 5), at("c", 13))
+
+		got := rivo.Collect(rivo.Pipe(in, window.Tumbling(10*time.Second, window.EventTime(eventTime)))(ctx, nil, nil))
+
+		if assert.Len(t, got, 2) {
+			assert.Equal(t, []string{"a"}, names(got[0]))
+			assert.Equal(t, []string{"b", "c"}, names(got[1]))
+		}
+	})
+
+	t.Run("processing time", func(t *testing.T) {
+		ctx := context.Background()
+
+		in := make(chan int)
+
+		go func() {
+			defer close(in)
+			in <- 1
+			in <- 2
+			time.Sleep(150 * time.Millisecond)
+			in <- 3
+		}()
+
+		start := time.Now()
+
+		out := window.Tumbling[int](100*time.Millisecond)(ctx, in, nil)
+
+		first := <-out
+		assert.Equal(t, []int{1, 2}, first.Items)
+		assert.Less(t, time.Since(start), 150*time.Millisecond, "the first window should be emitted when it ends")
+		assert.Equal(t, 100*time.Millisecond, first.End.Sub(first.Start))
+
+		rest := rivo.Collect(out)
+		if assert.Len(t, rest, 1) {
+			assert.Equal(t, []int{3}, rest[0].Items)
+		}
+	})
+
+	t.Run("context cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		in := make(chan int)
+
+		got := rivo.Collect(window.Tumbling[int](time.Second)(ctx, in, nil))
+
+		assert.Empty(t, got)
+	})
+
+	t.Run("with buffer size", func(t *testing.T) {
+		ctx := context.Background()
+
+		out := window.Tumbling(time.Second, window.BufferSize[int](2))(ctx, rivo.Of(1)(ctx, nil, nil), nil)
+
+		assert.Equal(t, 2, cap(out))
+		assert.Len(t, rivo.Collect(out), 1)
+	})
+
+	t.Run("invalid options", func(t *testing.T) {
+		assert.Panics(t, func() { window.Tumbling[int](0) })
+		assert.Panics(t, func() { window.Tumbling(time.Second, window.BufferSize[int](-1)) })
+		assert.Panics(t, func() { window.Tumbling(time.Second, window.EventTime[int](nil)) })
+	})
+}
+
+func TestSliding(t *testing.T) {
+	t.Run("event time", func(t *testing.T) {
+		ctx := context.Background()
+
+		in := rivo.Of(at("a", 1), at("b", 6), at("c", 12))
+
+		got := rivo.Collect(rivo.Pipe(in, window.Sliding(10*time.Second, 5*time.Second, window.EventTime(eventTime)))(ctx, nil, nil))
+
+		if assert.Len(t, got, 4) {
+			assert.Equal(t, t0.Add(-5*time.Second), got[0].Start)
+			assert.Equal(t, []string{"a"}, names(got[0]))
+			assert.Equal(t, t0, got[1].Start)
+			assert.Equal(t, []string{"a", "b"}, names(got[1]))
+			assert.Equal(t, t0.Add(5*time.Second), got[2].Start)
+			assert.Equal(t, []string{"b", "c"}, names(got[2]))
+			assert.Equal(t, t0.Add(10*time.Second), got[3].Start)
+			assert.Equal(t, []string{"c"}, names(got[3]))
+		}
+	})
+
+	t.Run("hop greater than size skips the items in the gaps", func(t *testing.T) {
+		ctx := context.Background()
+
+		in := rivo.Of(at("a", 1), at("gap", 7), at("b", 11))
+
+		late, lateOpt := window.LateItems[event]()
+
+		out := rivo.Pipe(in, window.Sliding(5*time.Second, 10*time.Second, window.EventTime(eventTime), lateOpt))(ctx, nil, nil)
+
+		lateItems := make(chan []event)
+		go func() {
+			lateItems <- rivo.Collect(late)
+		}()
+
+		got := rivo.Collect(out)
+
+		if assert.Len(t, got, 2) {
+			assert.Equal(t, t0, got[0].Start)
+			assert.Equal(t, []string{"a"}, names(got[0]))
+			assert.Equal(t, t0.Add(10*time.Second), got[1].Start)
+			assert.Equal(t, []string{"b"}, names(got[1]))
+		}
+
+		assert.Empty(t, <-lateItems)
+	})
+
+	t.Run("invalid options", func(t *testing.T) {
+		assert.Panics(t, func() { window.Sliding[int](0, time.Second) })
+		assert.Panics(t, func() { window.Sliding[int](time.Second, 0) })
+	})
+}
+
+func TestSession(t *testing.T) {
+	t.Run("event time", func(t *testing.T) {
+		ctx := context.Background()
+
+		in := rivo.Of(at("a", 0), at("b", 3), at("c", 6), at("d", 20), at("e", 22), at("f", 40))
+
+		got := rivo.Collect(rivo.Pipe(in, window.Session(5*time.Second, window.EventTime(eventTime)))(ctx, nil, nil))
+
+		if assert.Len(t, got, 3) {
+			assert.Equal(t, []string{"a", "b", "c"}, names(got[0]))
+			assert.Equal(t, t0, got[0].Start)
+			assert.Equal(t, t0.Add(11*time.Second), got[0].End)
+			assert.Equal(t, []string{"d", "e"}, names(got[1]))
+			assert.Equal(t, []string{"f"}, names(got[2]))
+		}
+	})
+
+	t.Run("out of order items merge sessions", func(t *testing.T) {
+		ctx := context.Background()
+
+		// b is out of order but its session overlaps a's, while late's session has already been emitted
+		in := rivo.Of(at("a", 10), at("b", 6), at("late", 0), at("c", 30))
+
+		got := rivo.Collect(rivo.Pipe(in, window.Session(5*time.Second, window.EventTime(eventTime)))(ctx, nil, nil))
+
+		if assert.Len(t, got, 2) {
+			assert.ElementsMatch(t, []string{"a", "b"}, names(got[0]))
+			assert.Equal(t, t0.Add(6*time.Second), got[0].Start)
+			assert.Equal(t, t0.Add(15*time.Second), got[0].End)
+			assert.Equal(t, []string{"c"}, names(got[1]))
+		}
+	})
+
+	t.Run("processing time", func(t *testing.T) {
+		ctx := context.Background()
+
+		in := make(chan int)
+
+		go func() {
+			defer close(in)
+			in <- 1
+			time.Sleep(20 * time.Millisecond)
+			in <- 2
+			time.Sleep(150 * time.Millisecond)
+			in <- 3
+		}()
+
+		got := rivo.Collect(window.Session[int](100*time.Millisecond)(ctx, in, nil))
+
+		if assert.Len(t, got, 2) {
+			assert.Equal(t, []int{1, 2}, got[0].Items)
+			assert.Equal(t, []int{3}, got[1].Items)
+		}
+	})
+
+	t.Run("invalid options", func(t *testing.T) {
+		assert.Panics(t, func() { window.Session[int](0) })
+	})
+}
+
+func TestDescribe(t *testing.T) {
+	late, lateOpt := window.LateItems[event]()
+
+	p := rivo.Pipe(window.Tumbling(10*time.Second, window.EventTime(eventTime), lateOpt), window.TumblingCount[window.Window[event]](2))
+
+	got := rivo.Describe(p)
+
+	assert.Equal(t, []rivo.TopologyNode{
+		{ID: 0, Kind: "window.Tumbling"},
+		{ID: 1, Kind: "window.TumblingCount"},
+		{ID: 2, Kind: "input"},
+		{ID: 3, Kind: "output"},
+	}, got.Nodes)
+	assert.Len(t, got.Edges, 3)
+
+	// Describing the pipeline leaves the late items stream open for the runs
+	var lateItems []event
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		lateItems = rivo.Collect(late)
+	}()
+
+	windows, err := rivo.RunCollect(context.Background(), rivo.Pipe(rivo.Of(at("a", 1), at("b", 12), at("late", 5), at("c", 25)), p))
+	assert.NoError(t, err)
+	assert.Len(t, windows, 2)
+
+	<-done
+	assert.Equal(t, []event{at("late", 5)}, lateItems)
+}